@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/spf13/cobra"
+)
+
+var kinds = []string{
+	cache.KindWinMower,
+	cache.KindSimulator,
+	cache.KindGSP,
+	cache.KindWinMowerFS,
+}
+
+func NewCacheCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clean up cached WinMowers, simulators, GSPs and WinMower filesystems",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newListCommand(gsCli),
+		newPruneCommand(gsCli),
+		newVerifyCommand(gsCli),
+		newRmCommand(gsCli),
+	)
+
+	return cmd
+}
+
+// cacheRoot returns the directory holding entries of kind and how many
+// levels below it the entries are.
+func cacheRoot(gsCli *cli.Cli, kind string) (string, int, error) {
+	switch kind {
+	case cache.KindWinMower:
+		return gsCli.Config.GetString("directories.winMowers"), 1, nil
+	case cache.KindSimulator:
+		return gsCli.Config.GetString("directories.simulator"), 1, nil
+	case cache.KindGSP:
+		return gsCli.Config.GetString("directories.gardenSimulatorPackets"), 2, nil
+	case cache.KindWinMowerFS:
+		return gsCli.Config.GetString("directories.winMowerFileSystems"), 1, nil
+	default:
+		return "", 0, fmt.Errorf("invalid cache kind: %s. Must be one of %v", kind, kinds)
+	}
+}
+
+// listEntries lists the entries of the given kinds, or of all kinds when none are given.
+func listEntries(gsCli *cli.Cli, only ...string) ([]cache.Entry, error) {
+	if len(only) == 0 {
+		only = kinds
+	}
+
+	var entries []cache.Entry
+	for _, kind := range only {
+		root, depth, err := cacheRoot(gsCli, kind)
+		if err != nil {
+			return nil, err
+		}
+		e, err := cache.ListEntries(kind, root, depth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s cache: %w", kind, err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+func removeEntry(gsCli *cli.Cli, e cache.Entry) error {
+	if e.Kind == cache.KindSimulator {
+		return gsCli.SimulatorRegistry.RemoveSimulator(e.Key)
+	}
+	return cache.RemoveEntry(e)
+}
+
+// parseAge accepts anything time.ParseDuration does plus a "d" suffix for days.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
+
+func kindArgs(cmd *cobra.Command) []string {
+	kind, _ := cmd.Flags().GetString("kind")
+	if kind == "" {
+		return nil
+	}
+	return []string{kind}
+}
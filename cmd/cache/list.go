@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newListCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cache entries with their size and when they were last used",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			entries, err := listEntries(gsCli, kindArgs(cmd)...)
+			if err != nil {
+				log.Error("Failed to list cache", "err", err)
+				return
+			}
+
+			var total int64
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tKEY\tVERSION\tSIZE\tLAST USED")
+			for _, e := range entries {
+				version := e.Version
+				if version == "" {
+					version = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Kind, e.Key, version, cache.FormatSize(e.Size), e.LastUsed.Format(time.DateTime))
+				total += e.Size
+			}
+			fmt.Fprintf(w, "\t\t\t%s\t\n", cache.FormatSize(total))
+			w.Flush()
+		},
+	}
+	cmd.Flags().String("kind", "", fmt.Sprintf("Only list entries of this kind %v", kinds))
+	return cmd
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newPruneCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cache entries by age or until the cache fits a size",
+		Long: `Remove cache entries that have not been used within --older-than, then remove
+the least recently used entries until the cache is no larger than --max-size.
+The active simulator and entries in use by a running session are never pruned.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			olderThan, _ := cmd.Flags().GetString("older-than")
+			maxSize, _ := cmd.Flags().GetString("max-size")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			if olderThan == "" && maxSize == "" {
+				log.Error("Nothing to prune, specify --older-than and/or --max-size")
+				return
+			}
+
+			entries, err := listEntries(gsCli, kindArgs(cmd)...)
+			if err != nil {
+				log.Error("Failed to list cache", "err", err)
+				return
+			}
+			active, err := gsCli.SimulatorRegistry.ActiveVersion()
+			if err != nil {
+				log.Error("Failed to read active simulator", "err", err)
+				return
+			}
+			cache.SortByLastUsed(entries)
+
+			var keep []cache.Entry
+			var prune []cache.Entry
+			for _, e := range entries {
+				if (e.Kind == cache.KindSimulator && e.Key == active) || cache.InUse(e.Dir) {
+					continue
+				}
+				keep = append(keep, e)
+			}
+
+			if olderThan != "" {
+				age, err := parseAge(olderThan)
+				if err != nil {
+					log.Error(err)
+					return
+				}
+				cutoff := time.Now().Add(-age)
+				var kept []cache.Entry
+				for _, e := range keep {
+					if e.LastUsed.Before(cutoff) {
+						prune = append(prune, e)
+					} else {
+						kept = append(kept, e)
+					}
+				}
+				keep = kept
+			}
+
+			if maxSize != "" {
+				limit, err := cache.ParseSize(maxSize)
+				if err != nil {
+					log.Error(err)
+					return
+				}
+				var total int64
+				for _, e := range entries {
+					total += e.Size
+				}
+				for _, e := range prune {
+					total -= e.Size
+				}
+				for len(keep) > 0 && total > limit {
+					prune = append(prune, keep[0])
+					total -= keep[0].Size
+					keep = keep[1:]
+				}
+			}
+
+			var freed int64
+			for _, e := range prune {
+				if dryRun {
+					fmt.Printf("would remove %s %s (%s)\n", e.Kind, e.Key, cache.FormatSize(e.Size))
+					continue
+				}
+				if err := removeEntry(gsCli, e); err != nil {
+					log.Error("Failed to remove cache entry", "kind", e.Kind, "key", e.Key, "err", err)
+					continue
+				}
+				freed += e.Size
+				log.Info("Removed cache entry", "kind", e.Kind, "key", e.Key, "size", cache.FormatSize(e.Size))
+			}
+			if !dryRun {
+				log.Info("Pruned cache", "entries", len(prune), "freed", cache.FormatSize(freed))
+			}
+		},
+	}
+	cmd.Flags().String("older-than", "", "Remove entries not used within this duration, e.g. 30d or 72h")
+	cmd.Flags().String("max-size", "", "Remove least recently used entries until the cache is no larger than this, e.g. 20GB")
+	cmd.Flags().String("kind", "", fmt.Sprintf("Only prune entries of this kind %v", kinds))
+	cmd.Flags().Bool("dry-run", false, "Print what would be removed without removing anything")
+	return cmd
+}
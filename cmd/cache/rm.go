@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newRmCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rm <kind> <key>",
+		Short: "Remove a single cache entry",
+		Long:  fmt.Sprintf("Remove a single cache entry. Kind is one of %v. GSP keys are <serial>/<platform>.", kinds),
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			kind, key := args[0], args[1]
+			entries, err := listEntries(gsCli, kind)
+			if err != nil {
+				log.Error("Failed to list cache", "err", err)
+				return
+			}
+
+			for _, e := range entries {
+				if e.Key != key {
+					continue
+				}
+				if err := removeEntry(gsCli, e); err != nil {
+					log.Error("Failed to remove cache entry", "kind", kind, "key", key, "err", err)
+					return
+				}
+				log.Info("Removed cache entry", "kind", kind, "key", key)
+				return
+			}
+			log.Error("No such cache entry", "kind", kind, "key", key)
+		},
+	}
+	return cmd
+}
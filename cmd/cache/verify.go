@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Re-hash cache entries and compare them against their recorded checksums",
+		Long:  ``,
+		// Corrupt entries are reported through the returned error so the exit
+		// code can be used in scripts.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := listEntries(gsCli, kindArgs(cmd)...)
+			if err != nil {
+				return err
+			}
+
+			corrupt := 0
+			for _, e := range entries {
+				if e.Manifest == nil || len(e.Manifest.Checksums) == 0 {
+					log.Debug("No checksums recorded", "kind", e.Kind, "key", e.Key)
+					continue
+				}
+
+				mismatches, err := cache.Verify(e.Dir, e.Manifest)
+				if err != nil {
+					return fmt.Errorf("failed to verify %s %s: %w", e.Kind, e.Key, err)
+				}
+				if len(mismatches) == 0 {
+					log.Info("OK", "kind", e.Kind, "key", e.Key)
+					continue
+				}
+
+				corrupt++
+				log.Error("Corrupt", "kind", e.Kind, "key", e.Key, "files", len(mismatches))
+				for _, m := range mismatches {
+					fmt.Printf("  %s: %s\n", m.Path, m.Reason)
+				}
+			}
+
+			if corrupt > 0 {
+				return fmt.Errorf("%d corrupt cache entries, remove them with `cache rm <kind> <key>`", corrupt)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("kind", "", fmt.Sprintf("Only verify entries of this kind %v", kinds))
+	return cmd
+}
@@ -13,4 +13,5 @@ type Cli struct {
 	SimulatorRegistry *robotics.SimulatorRegistry
 	BundleRegistry    *robotics.BundleRegistry
 	GSPRegistry       *robotics.GSPRegistry
+	PlatformDetector  *robotics.PlatformDetector
 }
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/spf13/cobra"
+)
+
+// CompletePlatforms completes the platforms of the catalog.
+func (c *Cli) CompletePlatforms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, p := range robotics.GetPlatforms() {
+		completions = append(completions, string(p)+"\t"+p.Info().DisplayName)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteSerials completes the serial numbers that have cached GSPs.
+func (c *Cli) CompleteSerials(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	serials, err := c.GSPRegistry.CachedSerials()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return serials, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteSerialArg completes a serial number as the first positional argument.
+func (c *Cli) CompleteSerialArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return c.CompleteSerials(cmd, args, toComplete)
+}
+
+// CompleteBundleTypes completes the WinMower bundle types of the platform
+// given with --platform, or of every platform. Cached bundle types are
+// offered when the bundle registry cannot be reached.
+func (c *Cli) CompleteBundleTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var platform robotics.Platform
+	if f := cmd.Flags().Lookup("platform"); f != nil {
+		platform = robotics.Platform(f.Value.String())
+	}
+
+	known := make(map[string]string)
+	entries, _ := cache.ListEntries(cache.KindWinMower, c.WinMowerRegistry.CacheDir, 1)
+	for _, e := range entries {
+		if e.Manifest != nil && e.Manifest.BundleType != "" && (platform == "" || e.Manifest.Platform == string(platform)) {
+			known[e.Manifest.BundleType] = "cached"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	types, err := c.BundleRegistry.FetchBundleTypes(ctx)
+	if err == nil {
+		if platform != "" {
+			types = robotics.FilterBundleTypes(types, platform)
+		}
+		for _, t := range types {
+			if _, ok := known[t.Name]; !ok {
+				known[t.Name] = t.Description
+			}
+		}
+	}
+
+	var completions []string
+	for name, desc := range known {
+		completions = append(completions, name+"\t"+desc)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
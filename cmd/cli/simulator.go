@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+)
+
+// SimulatorOptions returns the simulator options of platform with the ones
+// from config applied over them. The default config file holds every
+// built-in default, so a config value only counts once it differs from its
+// default.
+func (c *Cli) SimulatorOptions(platform robotics.Platform) (runner.SimulatorOptions, error) {
+	defaults := runner.DefaultSimulatorOptions()
+	o := defaults
+
+	platformOptions := platform.Info().DefaultSimulatorOptions()
+	names := make([]string, 0, len(platformOptions))
+	for name := range platformOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := o.Set(name, platformOptions[name]); err != nil {
+			return o, fmt.Errorf("platform %s: %w", platform, err)
+		}
+	}
+
+	if v := c.Config.GetBool("simulator.toLogNow"); v != defaults.Log {
+		o.Log = v
+	}
+	if v := c.Config.GetFloat64("simulator.timeScale"); v != defaults.TimeScale {
+		o.TimeScale = v
+	}
+	if v := c.Config.GetInt("simulator.screen.width"); v != defaults.ScreenWidth {
+		o.ScreenWidth = v
+	}
+	if v := c.Config.GetInt("simulator.screen.height"); v != defaults.ScreenHeight {
+		o.ScreenHeight = v
+	}
+	if v := c.Config.GetInt("simulator.qualityLevel"); v != defaults.QualityLevel {
+		o.QualityLevel = v
+	}
+	o.ExtraArgs = append(o.ExtraArgs, c.Config.GetStringSlice("simulator.extraArgs")...)
+	return o, nil
+}
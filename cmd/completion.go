@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script for gsim-web-launch.
+
+Bash:
+  source <(gsim-web-launch completion bash)
+
+Zsh:
+  gsim-web-launch completion zsh > "${fpath[1]}/_gsim-web-launch"
+
+Fish:
+  gsim-web-launch completion fish > ~/.config/fish/completions/gsim-web-launch.fish
+
+PowerShell:
+  gsim-web-launch completion powershell | Out-String | Invoke-Expression
+`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			default:
+				return root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+		},
+	}
+	return cmd
+}
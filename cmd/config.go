@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/viper"
 )
@@ -11,8 +18,14 @@ import (
 func setDefaults(cacheDir string) {
 	viper.SetDefault("endpoints.gardenSimulatorPacket", "https://hqvrobotics.azure-api.net/gardensimulatorpacket")
 	viper.SetDefault("endpoints.bundleStorage", "https://hqvrobotics.azure-api.net")
+	viper.SetDefault("endpoints.platformCatalog", "")
+	viper.SetDefault("endpoints.platformLookup", "")
+
+	viper.SetDefault("platforms", defaultPlatforms())
+	viper.SetDefault("platformDetection.rules", []map[string]any{})
 
 	viper.SetDefault("programs.tifConsole", filepath.Join(cacheDir, "TifApp/TifConsole.Auto.exe"))
+	viper.SetDefault("programs.startTriggerBundle", `C:\Repositories\GardenTVAutoLoader\GardenTVAutoloader\Resources\testscript.zip`)
 
 	viper.SetDefault("directories.appCacheDir", filepath.Join(cacheDir, "gsim"))
 	appCacheDir := viper.GetViper().GetString("directories.appCacheDir")
@@ -20,10 +33,66 @@ func setDefaults(cacheDir string) {
 	viper.SetDefault("directories.winMowerFileSystems", filepath.Join(appCacheDir, "winmower-filesystems"))
 	viper.SetDefault("directories.gardenSimulatorPackets", filepath.Join(appCacheDir, "gsp"))
 	viper.SetDefault("directories.simulator", filepath.Join(appCacheDir, "simulator"))
+	viper.SetDefault("directories.locks", filepath.Join(appCacheDir, "locks"))
+	viper.SetDefault("directories.logs", filepath.Join(appCacheDir, "logs"))
+
+	viper.SetDefault("updates.winMower.policy", "interval")
+	viper.SetDefault("updates.winMower.intervalHours", 24)
+	viper.SetDefault("updates.simulator.policy", "interval")
+	viper.SetDefault("updates.simulator.intervalHours", 24)
+
+	viper.SetDefault("cache.gsp.maxSize", "2GB")
+	viper.SetDefault("cache.gsp.maxEntries", 0)
+	viper.SetDefault("cache.gsp.ttl", "24h")
+	viper.SetDefault("cache.platformCatalog.ttl", "24h")
+
+	// addressArgs are passed to WinMower and the simulator with {address},
+	// {host} and {port} replaced by the WinMower address of the session.
+	// Addresses other than the built-in one of the platform need both.
+	viper.SetDefault("winMower.address", "")
+	viper.SetDefault("winMower.addressArgs", []string{})
+	viper.SetDefault("simulator.addressArgs", []string{})
+	// robotArgs are passed to the simulator once per robot with {serial} and
+	// {map} replaced as well. Launching several robots needs {map} in them.
+	viper.SetDefault("simulator.robotArgs", []string{})
+	viper.SetDefault("winMower.logPattern", runner.DefaultWinMowerLogPattern)
+	viper.SetDefault("tifConsole.results.casePattern", runner.DefaultTestCasePattern)
+	viper.SetDefault("tifConsole.results.stepPattern", runner.DefaultTestStepPattern)
+
+	viper.SetDefault("readiness.winMower.probe", "tcp")
+	viper.SetDefault("readiness.winMower.timeout", "30s")
+	viper.SetDefault("readiness.winMower.pattern", "")
+	viper.SetDefault("readiness.winMower.aliveFor", "3s")
+	viper.SetDefault("readiness.simulator.probe", "none")
+	viper.SetDefault("readiness.simulator.timeout", "60s")
+	viper.SetDefault("readiness.simulator.pattern", "")
+	viper.SetDefault("readiness.simulator.aliveFor", "3s")
+
+	viper.SetDefault("restart.winMower.maxRestarts", 0)
+	viper.SetDefault("restart.winMower.backoff", "2s")
+	viper.SetDefault("restart.winMower.maxBackoff", "30s")
+
+	simDefaults := runner.DefaultSimulatorOptions()
+	for _, process := range []string{"winMower", "tifConsole"} {
+		key := "logFilters." + process
+		viper.SetDefault(key+".moduleLevels", map[string]string{})
+		viper.SetDefault(key+".include", []string{})
+		viper.SetDefault(key+".exclude", []string{})
+		viper.SetDefault(key+".highlights", []map[string]string{})
+		viper.SetDefault(key+".collapse", false)
+	}
+
+	viper.SetDefault("triggers", []map[string]any{})
 
-	viper.SetDefault("simulator.toLogNow", false)
-	viper.SetDefault("simulator.screen.width", 1280)
-	viper.SetDefault("simulator.screen.height", 720)
+	viper.SetDefault("logs.retention.maxSessions", 20)
+	viper.SetDefault("logs.retention.maxAge", "720h")
+
+	viper.SetDefault("simulator.toLogNow", simDefaults.Log)
+	viper.SetDefault("simulator.timeScale", simDefaults.TimeScale)
+	viper.SetDefault("simulator.screen.width", simDefaults.ScreenWidth)
+	viper.SetDefault("simulator.screen.height", simDefaults.ScreenHeight)
+	viper.SetDefault("simulator.qualityLevel", simDefaults.QualityLevel)
+	viper.SetDefault("simulator.extraArgs", []string{})
 }
 
 func initConfig() {
@@ -52,3 +121,176 @@ func initConfig() {
 		}
 	}
 }
+
+func updatePolicy(v *viper.Viper, artifact string) (robotics.UpdatePolicy, error) {
+	key := "updates." + artifact
+	policy, err := robotics.ParseUpdatePolicy(v.GetString(key+".policy"), v.GetInt(key+".intervalHours"))
+	if err != nil {
+		return robotics.UpdatePolicy{}, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return policy, nil
+}
+
+func defaultPlatforms() []map[string]any {
+	var platforms []map[string]any
+	for _, info := range robotics.DefaultPlatformInfos() {
+		platforms = append(platforms, map[string]any{
+			"name":              string(info.Name),
+			"displayName":       info.DisplayName,
+			"bundleTypePattern": info.BundleTypePattern,
+			"winMowerPort":      info.WinMowerPort,
+		})
+	}
+	return platforms
+}
+
+// platformCatalogFile caches the remote platform catalog in the app cache
+// dir so not every invocation waits for it.
+const platformCatalogFile = "platform-catalog.json"
+
+type cachedPlatformCatalog struct {
+	Url       string                  `json:"url"`
+	FetchedAt time.Time               `json:"fetchedAt"`
+	Platforms []robotics.PlatformInfo `json:"platforms"`
+}
+
+// loadPlatformCatalog reads the platforms from config and, when configured,
+// merges the remote catalog over them. A failing remote catalog is not fatal.
+// With offline set only a cached remote catalog is used, so shell completion
+// never waits for the network.
+func loadPlatformCatalog(v *viper.Viper, offline bool) (*robotics.PlatformCatalog, error) {
+	var infos []robotics.PlatformInfo
+	if err := v.UnmarshalKey("platforms", &infos); err != nil {
+		return nil, fmt.Errorf("invalid platforms: %w", err)
+	}
+
+	if url := v.GetString("endpoints.platformCatalog"); url != "" {
+		remote, err := remotePlatformCatalog(v, url, offline)
+		if err != nil {
+			log.Warn("Failed to fetch platform catalog, using configured platforms", "err", err)
+		} else {
+			infos = append(infos, remote...)
+		}
+	}
+
+	return robotics.NewPlatformCatalog(infos)
+}
+
+// remotePlatformCatalog returns the catalog at url from the cache, fetching
+// it again once the cached copy is older than cache.platformCatalog.ttl. The
+// cached copy is used when fetching fails.
+func remotePlatformCatalog(v *viper.Viper, url string, offline bool) ([]robotics.PlatformInfo, error) {
+	ttl, err := time.ParseDuration(v.GetString("cache.platformCatalog.ttl"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache.platformCatalog.ttl: %w", err)
+	}
+	path := filepath.Join(v.GetString("directories.appCacheDir"), platformCatalogFile)
+
+	var cached cachedPlatformCatalog
+	hasCached := false
+	if b, err := os.ReadFile(path); err == nil && json.Unmarshal(b, &cached) == nil && cached.Url == url {
+		hasCached = true
+	}
+	if hasCached && (offline || time.Since(cached.FetchedAt) < ttl) {
+		return cached.Platforms, nil
+	}
+	if offline {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	remote, err := robotics.FetchPlatformCatalog(ctx, url)
+	if err != nil {
+		if hasCached {
+			log.Warn("Failed to fetch platform catalog, using the cached one", "fetchedAt", cached.FetchedAt, "err", err)
+			return cached.Platforms, nil
+		}
+		return nil, err
+	}
+
+	b, err := json.Marshal(cachedPlatformCatalog{Url: url, FetchedAt: time.Now(), Platforms: remote})
+	if err == nil {
+		err = os.WriteFile(path, b, 0644)
+	}
+	if err != nil {
+		log.Warn("Failed to cache platform catalog", "err", err)
+	}
+	return remote, nil
+}
+
+func platformDetector(v *viper.Viper) (*robotics.PlatformDetector, error) {
+	var rules []robotics.PlatformRule
+	if err := v.UnmarshalKey("platformDetection.rules", &rules); err != nil {
+		return nil, fmt.Errorf("invalid platformDetection.rules: %w", err)
+	}
+	return &robotics.PlatformDetector{
+		Rules:     rules,
+		LookupUrl: v.GetString("endpoints.platformLookup"),
+	}, nil
+}
+
+// readinessProbe returns the configured probe for process and how long to
+// wait for it. A nil probe means the process is used as soon as it started.
+// address is what the tcp probe connects to.
+func readinessProbe(v *viper.Viper, process, address string) (runner.Probe, time.Duration, error) {
+	key := "readiness." + process
+	timeout, err := time.ParseDuration(v.GetString(key + ".timeout"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid %s.timeout: %w", key, err)
+	}
+
+	switch kind := v.GetString(key + ".probe"); kind {
+	case "", "none":
+		return nil, 0, nil
+	case "tcp":
+		if address == "" {
+			return nil, 0, fmt.Errorf("invalid %s.probe: %s has no address to connect to", key, process)
+		}
+		return &runner.TCPProbe{Address: address}, timeout, nil
+	case "log":
+		pattern, err := regexp.Compile(v.GetString(key + ".pattern"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid %s.pattern: %w", key, err)
+		}
+		return runner.NewLogProbe(pattern), timeout, nil
+	case "alive":
+		aliveFor, err := time.ParseDuration(v.GetString(key + ".aliveFor"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid %s.aliveFor: %w", key, err)
+		}
+		return &runner.AliveProbe{Duration: aliveFor}, timeout, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid %s.probe: unknown probe %q, expected none, tcp, log or alive", key, kind)
+	}
+}
+
+func restartPolicy(v *viper.Viper, process string) (runner.RestartPolicy, error) {
+	key := "restart." + process
+	backoff, err := time.ParseDuration(v.GetString(key + ".backoff"))
+	if err != nil {
+		return runner.RestartPolicy{}, fmt.Errorf("invalid %s.backoff: %w", key, err)
+	}
+	maxBackoff, err := time.ParseDuration(v.GetString(key + ".maxBackoff"))
+	if err != nil {
+		return runner.RestartPolicy{}, fmt.Errorf("invalid %s.maxBackoff: %w", key, err)
+	}
+	return runner.RestartPolicy{
+		MaxRestarts: v.GetInt(key + ".maxRestarts"),
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+	}, nil
+}
+
+func logFilter(v *viper.Viper, process string) (*runner.Filter, error) {
+	key := "logFilters." + process
+	var rules runner.FilterRules
+	if err := v.UnmarshalKey(key, &rules); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	f, err := runner.NewFilter(rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
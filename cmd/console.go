@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+	"github.com/charmbracelet/log"
+)
+
+const consoleHelp = `Commands:
+  <target> level <module|*> <level>   hide entries of module below level
+  <target> include <regex>            only show lines matching regex
+  <target> exclude <regex>            hide lines matching regex
+  <target> highlight <regex> [color]  color the parts of lines matching regex
+  <target> collapse on|off            show repeated lines once with a count
+  <target> reset                      go back to the configured rules
+  rules                               show the current rules
+  pause, resume                       hold back process output and show it again
+  help                                show this help
+  quit, or an empty line              stop all processes and exit
+Targets: %s`
+
+// runConsole reads commands that change the log filters from stdin until the
+// user exits or ctx is done.
+func runConsole(ctx context.Context, filters map[string]*runner.Filter) {
+	defer terminalOut.Resume()
+	targets := filterNames(filters)
+
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				close(lines)
+				return
+			}
+			lines <- strings.TrimSpace(line)
+		}
+	}()
+
+	log.Info("Type a command to change what is shown, help to list commands, or press enter to exit...")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-lines:
+			if !ok || line == "" || line == "quit" || line == "q" {
+				return
+			}
+			if err := consoleCommand(line, filters); err != nil {
+				log.Error(err)
+			}
+			if line == "help" {
+				fmt.Printf(consoleHelp+"\n", strings.Join(targets, ", "))
+			}
+		}
+	}
+}
+
+func consoleCommand(line string, filters map[string]*runner.Filter) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "help":
+		return nil
+	case "pause":
+		terminalOut.Pause()
+		return nil
+	case "resume":
+		terminalOut.Resume()
+		return nil
+	case "rules":
+		for _, name := range filterNames(filters) {
+			fmt.Printf("%s:\n%s", name, filters[name])
+		}
+		return nil
+	}
+
+	f, ok := filters[fields[0]]
+	if !ok || len(fields) < 2 {
+		return fmt.Errorf("unknown command %q, type help to list commands", line)
+	}
+	args := fields[2:]
+	var err error
+	switch cmd := fields[1]; {
+	case cmd == "level" && len(args) == 2:
+		err = f.SetModuleLevel(args[0], args[1])
+	case cmd == "include" && len(args) == 1:
+		err = f.Include(args[0])
+	case cmd == "exclude" && len(args) == 1:
+		err = f.Exclude(args[0])
+	case cmd == "highlight" && len(args) == 1:
+		err = f.Highlight(args[0], "")
+	case cmd == "highlight" && len(args) == 2:
+		err = f.Highlight(args[0], args[1])
+	case cmd == "collapse" && len(args) == 1 && (args[0] == "on" || args[0] == "off"):
+		f.SetCollapse(args[0] == "on")
+	case cmd == "reset" && len(args) == 0:
+		err = f.Reset()
+	default:
+		return fmt.Errorf("unknown command %q, type help to list commands", line)
+	}
+	if err == nil {
+		log.Info("Updated log rules", "target", fields[0])
+	}
+	return err
+}
+
+func filterNames(filters map[string]*runner.Filter) []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,24 @@
+package gsp
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewGSPCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gsp",
+		Short: "Work with Garden Simulator Packets",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newInspectCommand(gsCli),
+		newImportCommand(gsCli),
+	)
+
+	return cmd
+}
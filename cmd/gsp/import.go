@@ -0,0 +1,39 @@
+package gsp
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand(gsCli *cli.Cli) *cobra.Command {
+	var platform robotics.Platform
+	cmd := &cobra.Command{
+		Use:   "import <zip|dir>",
+		Short: "Add a GSP from a local zip file or directory to the cache",
+		Long: `Add a GSP from a local zip file or directory to the cache so it is used
+instead of downloading one. The serial number is detected from the test
+bundle in the packet unless --serial-number is given.
+
+GSPs cached by earlier versions without a known platform are kept under
+<serial>/unknown and never launched. Import that directory with --platform
+to claim it for a platform.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			serialNumber, _ := cmd.Flags().GetString("serial-number")
+
+			gsp, err := gsCli.GSPRegistry.ImportGSP(args[0], serialNumber, string(platform))
+			if err != nil {
+				log.Error("Failed to import GSP", "err", err)
+				return
+			}
+			log.Info("Imported GSP", "platform", platform, "dir", gsp.Dir)
+		},
+	}
+	cmd.Flags().StringP("serial-number", "s", "", "Serial number of the device the GSP belongs to")
+	cmd.Flags().VarP(&platform, "platform", "p", "Platform of the device the GSP belongs to")
+	cmd.MarkFlagRequired("platform")
+	cmd.RegisterFlagCompletionFunc("platform", gsCli.CompletePlatforms)
+	return cmd
+}
@@ -0,0 +1,104 @@
+package gsp
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newInspectCommand(gsCli *cli.Cli) *cobra.Command {
+	var platform robotics.Platform
+	cmd := &cobra.Command{
+		Use:               "inspect <serial>",
+		Short:             "Validate and summarize the garden map of a cached GSP",
+		Long:              ``,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: gsCli.CompleteSerialArg,
+		Run: func(cmd *cobra.Command, args []string) {
+			serialNumber := args[0]
+			if platform == "" {
+				platforms, err := gsCli.GSPRegistry.CachedPlatforms(serialNumber)
+				if err != nil {
+					log.Error("Failed to read GSP cache", "err", err)
+					return
+				}
+				if len(platforms) != 1 {
+					log.Error("Specify the platform with --platform", "serial", serialNumber, "cached", platforms)
+					return
+				}
+				platform = robotics.Platform(platforms[0])
+			}
+
+			gsp, err := gsCli.GSPRegistry.GetGSPFromCache(serialNumber, string(platform))
+			if err != nil {
+				log.Error("Failed to read cached GSP", "err", err)
+				return
+			}
+			if gsp == nil {
+				log.Error("No cached GSP", "serial", serialNumber, "platform", platform)
+				return
+			}
+
+			// The map is summarized as far as it could be read, even when it
+			// does not pass validation.
+			gardenMap, err := robotics.ParseGardenMap(gsp.Map)
+			if gardenMap != nil {
+				printSummary(serialNumber, string(platform), gsp, gardenMap)
+			}
+			if err != nil {
+				log.Error("GSP map did not pass validation", "err", err)
+			}
+		},
+	}
+	cmd.Flags().VarP(&platform, "platform", "p", "Platform of the cached GSP, needed when the serial has several")
+	cmd.RegisterFlagCompletionFunc("platform", gsCli.CompletePlatforms)
+	return cmd
+}
+
+func printSummary(serialNumber, platform string, gsp *robotics.GSPPaths, m *robotics.GardenMap) {
+	min, max := m.Bounds()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Serial\t%s\n", serialNumber)
+	fmt.Fprintf(w, "Platform\t%s\n", platform)
+	if m.Name != "" {
+		fmt.Fprintf(w, "Name\t%s\n", m.Name)
+	}
+	fmt.Fprintf(w, "Size\t%.1f x %.1f m\n", max.X-min.X, max.Y-min.Y)
+	fmt.Fprintf(w, "Map\t%s\n", gsp.Map)
+	fmt.Fprintf(w, "Test bundle\t%s\n", gsp.TestBundle)
+
+	var total float64
+	for _, a := range m.WorkAreas {
+		total += a.Area()
+	}
+	fmt.Fprintf(w, "Work areas\t%d (%.1f m²)\n", len(m.WorkAreas), total)
+	for i, a := range m.WorkAreas {
+		name := a.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		fmt.Fprintf(w, "  %s\t%.1f m², %d points\n", name, a.Area(), len(a.Polygon))
+	}
+
+	fmt.Fprintf(w, "Boundary wires\t%d\n", len(m.BoundaryWires))
+	for i, wire := range m.BoundaryWires {
+		fmt.Fprintf(w, "  #%d\t%.1f m\n", i+1, wire.Length(true))
+	}
+	fmt.Fprintf(w, "Guide wires\t%d\n", len(m.GuideWires))
+	for i, wire := range m.GuideWires {
+		fmt.Fprintf(w, "  #%d\t%.1f m\n", i+1, wire.Length(false))
+	}
+
+	if cs := m.ChargingStation; cs != nil {
+		fmt.Fprintf(w, "Charging station\t(%.2f, %.2f) heading %.0f°\n", cs.Position.X, cs.Position.Y, cs.Heading)
+	} else {
+		fmt.Fprintf(w, "Charging station\tnone\n")
+	}
+	w.Flush()
+}
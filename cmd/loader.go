@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
 	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -16,6 +17,7 @@ type model struct {
 	errChan  chan error
 	msgChan  chan progressMsg
 	text     string
+	notes    []string
 	progress progress.Model
 	spinner  spinner.Model
 	width    int
@@ -69,6 +71,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, progressCmd
 		}
 
+		if pm.note != "" {
+			m.notes = append(m.notes, pm.note)
+		}
 		m.text = string(fmt.Sprintf("%s", pm.text))
 		progressCmd := m.progress.SetPercent(float64(pm.percent) / 100.0)
 		return m, tea.Batch(
@@ -110,8 +115,7 @@ func (m model) View() string {
 	m.progress.Width = m.width / 3
 	prog := m.progress.View()
 
-	block := lipgloss.JoinVertical(
-		lipgloss.Left,
+	lines := []string{
 		lipgloss.NewStyle().
 			Margin(1, 0).
 			Foreground(lipgloss.Color("#ffffff")).
@@ -119,9 +123,16 @@ func (m model) View() string {
 		lipgloss.NewStyle().
 			MarginBottom(1).
 			Foreground(lipgloss.Color("#aaaaaa")).
-			Render(m.spinner.View()+m.text),
-		prog,
-	)
+			Render(m.spinner.View() + m.text),
+	}
+	for _, note := range m.notes {
+		lines = append(lines, lipgloss.NewStyle().
+			MarginBottom(1).
+			Foreground(lipgloss.Color("#f59e0b")).
+			Render("! "+note))
+	}
+	lines = append(lines, prog)
+	block := lipgloss.JoinVertical(lipgloss.Left, lines...)
 	return lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("#8b5cf6")).
@@ -134,52 +145,135 @@ type progressMsg struct {
 	text    string
 	percent int
 	isError bool
+	// note is kept on screen for the rest of the preparation.
+	note string
 }
 
 func prepareRuntime(msgChan chan progressMsg, resChan chan runtimeConfig, errChan chan error) tea.Cmd {
 	return func() tea.Msg {
-		msgChan <- progressMsg{text: fmt.Sprintf("Downloading and unpacking %s winmower...", platform), percent: 0}
-		p := robotics.Platform(platform)
-		winMower, err := gsCli.WinMowerRegistry.GetWinMower(p, context.Background())
-		if err != nil {
-			msgChan <- progressMsg{text: "Failed to get winmower", isError: true}
-			errChan <- err
-			return nil
-		}
-		if winMower == nil {
-			msgChan <- progressMsg{text: fmt.Sprintf("No winmower found for platform %s", platform), isError: true}
-			errChan <- fmt.Errorf("no winmower found for platform %s", platform)
-			return nil
+		var prepared []robotRuntime
+		var gspNote string
+		step := 60 / len(robots)
+		for i, r := range robots {
+			rt, err := prepareRobot(r, i*step, step, msgChan)
+			if err != nil {
+				releaseGSPs(append(prepared, rt))
+				errChan <- err
+				return nil
+			}
+			if rt.GSPPaths.Stale {
+				gspNote = "Using a stale Garden Simulator Packet, it could not be refreshed"
+			}
+			prepared = append(prepared, rt)
 		}
 
-		msgChan <- progressMsg{text: "Fetching the Garden Simulator Packet...", percent: 30}
-		gspPaths, err := gsCli.GSPRegistry.GetGSP(serialNumber, platform)
-		if err != nil {
-			msgChan <- progressMsg{text: fmt.Sprintf("Failed to download and unpack GSP: %s", err), isError: true}
-			errChan <- err
-			return nil
+		msgChan <- progressMsg{text: "Downloading and unpacking Garden Simulator...", percent: 60, note: gspNote}
+		var simulator *robotics.Simulator
+		var err error
+		switch {
+		case launchLock != nil:
+			simulator, err = gsCli.SimulatorRegistry.GetSimulatorVersion(context.Background(), launchLock.Simulator.BuildId)
+		case simVersion != "":
+			simulator, err = gsCli.SimulatorRegistry.GetSimulatorVersion(context.Background(), simVersion)
+		default:
+			simulator, err = gsCli.SimulatorRegistry.GetSimulator(context.Background())
 		}
-
-		msgChan <- progressMsg{text: "Downloading and unpacking Garden Simulator...", percent: 60}
-		simulator, err := gsCli.SimulatorRegistry.GetSimulator(context.Background())
 		if err != nil {
 			msgChan <- progressMsg{text: fmt.Sprintf("Failed to get simulator: %s", err), isError: true}
 			errChan <- err
 			return nil
 		}
 
+		startTrigger := gsCli.Config.GetString("programs.startTriggerBundle")
+		if launchLock != nil {
+			startTrigger = launchLock.StartTriggerBundle
+			if err := verifyStartTrigger(startTrigger, launchLock.StartTriggerHash); err != nil {
+				msgChan <- progressMsg{text: err.Error(), isError: true}
+				errChan <- err
+				return nil
+			}
+		}
+
 		msgChan <- progressMsg{text: "Preparation complete", percent: 100}
 
 		resChan <- runtimeConfig{
-			Winmower:  winMower,
-			Simulator: simulator,
-			GSPPaths:  gspPaths,
+			Robots:             prepared,
+			Simulator:          simulator,
+			StartTriggerBundle: startTrigger,
 		}
 
 		return nil
 	}
 }
 
+// prepareRobot gets the WinMower and GSP of r, reporting progress from
+// percent up to percent+step.
+func prepareRobot(r robot, percent, step int, msgChan chan progressMsg) (robotRuntime, error) {
+	rt := robotRuntime{robot: r}
+	msgChan <- progressMsg{text: fmt.Sprintf("Downloading and unpacking %s winmower...", r.Platform), percent: percent}
+	var err error
+	if launchLock != nil {
+		rt.Winmower, err = gsCli.WinMowerRegistry.GetWinMowerBuild(r.Platform, launchLock.WinMower.BundleType, launchLock.WinMower.BuildId, context.Background())
+	} else {
+		rt.Winmower, err = gsCli.WinMowerRegistry.GetWinMower(r.Platform, context.Background())
+	}
+	if err != nil {
+		msgChan <- progressMsg{text: "Failed to get winmower", isError: true}
+		return rt, err
+	}
+	if rt.Winmower == nil {
+		msgChan <- progressMsg{text: fmt.Sprintf("No winmower found for platform %s", r.Platform), isError: true}
+		return rt, fmt.Errorf("no winmower found for platform %s", r.Platform)
+	}
+
+	percent += step / 2
+	if gspFile != "" {
+		msgChan <- progressMsg{text: "Importing the Garden Simulator Packet...", percent: percent}
+		rt.GSPPaths, err = gsCli.GSPRegistry.ImportGSP(gspFile, r.Serial, string(r.Platform))
+		if err != nil {
+			msgChan <- progressMsg{text: fmt.Sprintf("Failed to import GSP: %s", err), isError: true}
+			return rt, err
+		}
+	} else {
+		msgChan <- progressMsg{text: fmt.Sprintf("Fetching the Garden Simulator Packet of %s...", r.Serial), percent: percent}
+		rt.GSPPaths, err = gsCli.GSPRegistry.GetGSP(r.Serial, string(r.Platform))
+		if err != nil {
+			msgChan <- progressMsg{text: fmt.Sprintf("Failed to download and unpack GSP: %s", err), isError: true}
+			return rt, err
+		}
+	}
+
+	// Protected right away, so preparing the next robot does not evict it.
+	if release, err := cache.MarkInUse(rt.GSPPaths.Dir); err != nil {
+		msgChan <- progressMsg{text: "Failed to protect the Garden Simulator Packet from eviction", percent: percent, note: fmt.Sprintf("GSP of %s may be evicted: %s", r.Serial, err)}
+	} else {
+		rt.releaseGSP = release
+	}
+
+	if launchLock != nil {
+		hash, err := gspHash(rt.GSPPaths.Dir)
+		if err != nil {
+			msgChan <- progressMsg{text: fmt.Sprintf("Failed to verify GSP: %s", err), isError: true}
+			return rt, err
+		}
+		if hash != launchLock.GSPHash {
+			err := fmt.Errorf("GSP for %s differs from the locked one (hash %s, locked %s)", r.Serial, hash, launchLock.GSPHash)
+			msgChan <- progressMsg{text: err.Error(), isError: true}
+			return rt, err
+		}
+	}
+	return rt, nil
+}
+
+// releaseGSPs ends the protection of the GSPs of robots from eviction.
+func releaseGSPs(robots []robotRuntime) {
+	for _, r := range robots {
+		if r.releaseGSP != nil {
+			r.releaseGSP()
+		}
+	}
+}
+
 func receiveProgressMsg(mshChan chan progressMsg) tea.Cmd {
 	return func() tea.Msg {
 		return <-mshChan
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+	"github.com/Tifufu/gsim-web-launch/pkg/session"
+)
+
+// lockMapArg stands for the map of the locked GSP in the recorded simulator
+// arguments, as its path differs between machines.
+const lockMapArg = "{map}"
+
+// lockAddress stands for the WinMower address in the recorded arguments, as
+// it is resolved again for every launch. Expanding it keeps the address
+// placeholders, so it also works for arguments that only use {port}.
+const lockAddress = "{host}:{port}"
+
+// writeLockfile records what a launch of a single robot resolved to and
+// returns its path.
+func writeLockfile(cli *cli.Cli, runtime runtimeConfig, simOptions runner.SimulatorOptions) (string, error) {
+	r := runtime.Robots[0]
+	if r.Winmower.BuildId == "" {
+		return "", fmt.Errorf("winmower of %s has no known build, launch with --update to record one", r.Platform)
+	}
+	hash, err := gspHash(r.GSPPaths.Dir)
+	if err != nil {
+		return "", err
+	}
+	startTriggerHash, err := cache.HashFile(runtime.StartTriggerBundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash start trigger bundle: %w", err)
+	}
+
+	// The log file belongs to the session, not to what was launched.
+	simOptions.LogFile = ""
+	locked := r
+	locked.Address = lockAddress
+	lockedGSP := *r.GSPPaths
+	lockedGSP.Map = lockMapArg
+	locked.GSPPaths = &lockedGSP
+	simArgs := simulatorArgs(cli, simOptions, lockMapArg, []robotRuntime{locked})
+	if launchLock != nil {
+		simArgs = launchLock.SimulatorArgs
+	}
+
+	now := time.Now()
+	lock := &session.Lock{
+		CreatedAt:    now,
+		SerialNumber: r.Serial,
+		Platform:     string(r.Platform),
+		WinMower: session.Build{
+			BundleType: r.Winmower.BundleType,
+			BuildId:    r.Winmower.BuildId,
+		},
+		Simulator: session.Build{
+			BundleType: "GardenSimulator",
+			BuildId:    runtime.Simulator.BuildId,
+		},
+		GSPHash:            hash,
+		StartTriggerBundle: runtime.StartTriggerBundle,
+		StartTriggerHash:   startTriggerHash,
+		WinMowerAddress:    r.AddressSpec,
+		WinMowerArgs:       winMowerArgs(cli, locked.robot),
+		SimulatorArgs:      simArgs,
+		TestBundleArgs:     tifArgs(locked.robot),
+		SimulatorOptions:   &simOptions,
+	}
+
+	dir := cli.Config.GetString("directories.locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, session.LockName(r.Serial, string(r.Platform), now))
+	return path, session.WriteLock(path, lock)
+}
+
+func gspHash(dir string) (string, error) {
+	sums, err := cache.HashDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash GSP: %w", err)
+	}
+	return cache.Digest(sums), nil
+}
+
+// replaySimulatorArgs returns the simulator arguments recorded in a lockfile
+// for the map at mapPath and the WinMower at address, writing the simulator
+// log to logFile if set.
+func replaySimulatorArgs(lock *session.Lock, mapPath, address, logFile string) []string {
+	args := make([]string, 0, len(lock.SimulatorArgs)+2)
+	for _, arg := range runner.ExpandArgs(lock.SimulatorArgs, address) {
+		args = append(args, strings.ReplaceAll(arg, lockMapArg, mapPath))
+	}
+	if logFile != "" {
+		args = append(args, "-logFile", logFile)
+	}
+	return args
+}
+
+// verifyStartTrigger checks the start trigger bundle against the hash of a
+// lockfile.
+func verifyStartTrigger(path, locked string) error {
+	hash, err := cache.HashFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify start trigger bundle: %w", err)
+	}
+	if hash != locked {
+		return fmt.Errorf("start trigger bundle %s differs from the locked one (hash %s, locked %s)", path, hash, locked)
+	}
+	return nil
+}
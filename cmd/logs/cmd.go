@@ -0,0 +1,23 @@
+package logs
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewLogsCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Find the logs of earlier sessions",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newPathCommand(gsCli),
+	)
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package logs
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/session"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newPathCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the log directory of the latest session",
+		Long: `Print the log directory of the latest session. With --combined the
+combined log of the session is printed instead, with --root the directory
+all session logs are kept in.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			root := gsCli.Config.GetString("directories.logs")
+			if r, _ := cmd.Flags().GetBool("root"); r {
+				fmt.Println(root)
+				return
+			}
+
+			dirs, err := session.LogDirs(root)
+			if err != nil {
+				log.Error("Failed to list session logs", "err", err)
+				return
+			}
+			if len(dirs) == 0 {
+				log.Error("No session logs yet", "dir", root)
+				return
+			}
+
+			if combined, _ := cmd.Flags().GetBool("combined"); combined {
+				fmt.Println(filepath.Join(dirs[0], session.CombinedLogName))
+				return
+			}
+			fmt.Println(dirs[0])
+		},
+	}
+	cmd.Flags().Bool("combined", false, "Print the combined log file of the latest session")
+	cmd.Flags().Bool("root", false, "Print the directory all session logs are kept in")
+	cmd.MarkFlagsMutuallyExclusive("combined", "root")
+	return cmd
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+	"github.com/charmbracelet/log"
+)
+
+var (
+	// reportRuns counts the runs of each test bundle process so a bundle run
+	// again after a restart does not overwrite the report of the first run.
+	reportRuns   = map[string]int{}
+	reportRunsMu sync.Mutex
+)
+
+// runTestBundle runs b under sup as name, then shows a summary of its results
+// and writes them next to the session logs.
+func runTestBundle(ctx context.Context, sup *runner.Supervisor, name string, b *runner.TestBundle, dependsOn ...string) error {
+	err := sup.Run(ctx, name, b, dependsOn...)
+	if err != nil && b.Status() == runner.StatusStopped && ctx.Err() == nil {
+		// Stopped by a run of the same bundle after a WinMower restart.
+		log.Warn("Test bundle was stopped to run it again", "process", name)
+		return nil
+	}
+	results := b.Results()
+	if results == nil {
+		return err
+	}
+
+	logResults(name, results)
+	if sessionLogs != nil {
+		if err := writeResults(sessionLogs.Path, name, results); err != nil {
+			log.Warn("Failed to write test results", "bundle", results.Bundle, "err", err)
+		}
+	}
+	return err
+}
+
+func logResults(name string, r *runner.TestResults) {
+	passed, failed, skipped := r.Counts()
+	keyvals := []any{
+		"process", name,
+		"bundle", r.Bundle,
+		"cases", len(r.Cases),
+		"passed", passed,
+		"failed", failed,
+		"skipped", skipped,
+		"duration", r.Duration.Round(100 * time.Millisecond),
+	}
+	if !r.Failed() {
+		log.Info("Test bundle passed", keyvals...)
+		return
+	}
+
+	log.Error("Test bundle failed", append(keyvals, "exitCode", r.ExitCode)...)
+	for _, c := range r.Cases {
+		for _, s := range c.Steps {
+			if s.Status == runner.TestFailed {
+				log.Error("Test step failed", "case", c.Name, "step", s.Name, "message", s.Message)
+			}
+		}
+	}
+}
+
+// writeResults writes r as JUnit XML and JSON into dir.
+func writeResults(dir, name string, r *runner.TestResults) error {
+	base := strings.ReplaceAll(name, ":", "-")
+	reportRunsMu.Lock()
+	reportRuns[base]++
+	if run := reportRuns[base]; run > 1 {
+		base = fmt.Sprintf("%s-%d", base, run)
+	}
+	reportRunsMu.Unlock()
+
+	f, err := os.Create(filepath.Join(dir, base+"-results.xml"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := runner.WriteJUnit(f, r); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, base+"-results.json"), data, 0644)
+}
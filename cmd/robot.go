@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+)
+
+// robot is one mower of a launch. Every robot gets its own WinMower in the
+// shared simulator.
+type robot struct {
+	Serial   string
+	Platform robotics.Platform
+	// Address is the WinMower address once it has been resolved from
+	// AddressSpec, which is auto or a host:port.
+	Address     string
+	AddressSpec string
+}
+
+type robotRuntime struct {
+	robot
+	Winmower *robotics.WinMower
+	GSPPaths *robotics.GSPPaths
+	// releaseGSP ends the protection of the GSP from eviction, if it is
+	// protected.
+	releaseGSP func()
+}
+
+// parseRobot parses a --robot value of the form serial[:platform].
+func parseRobot(spec string) (robot, error) {
+	serial, p, _ := strings.Cut(spec, ":")
+	if serial == "" {
+		return robot{}, fmt.Errorf("invalid robot %q, expected serial[:platform]", spec)
+	}
+	r := robot{Serial: serial}
+	if p != "" {
+		if err := r.Platform.Set(p); err != nil {
+			return robot{}, fmt.Errorf("invalid robot %q: %w", spec, err)
+		}
+	}
+	return r, nil
+}
+
+// processName returns the supervisor name of a process that belongs to r.
+func (r robot) processName(process string) string {
+	return process + ":" + r.Serial
+}
+
+// fsDirName returns the name of the WinMower filesystem dir of r. A robot
+// launched alone keeps the per-platform dir earlier launches used.
+func (r robot) fsDirName(alone bool) string {
+	if alone {
+		return r.Platform.String()
+	}
+	return r.Platform.String() + "-" + r.Serial
+}
+
+// expandArgs replaces {serial} and {map} in args, and the address
+// placeholders of runner.ExpandArgs, with the values of r.
+func (r robotRuntime) expandArgs(args []string) []string {
+	expanded := runner.ExpandArgs(args, r.Address)
+	replacer := strings.NewReplacer("{serial}", r.Serial, "{map}", r.GSPPaths.Map)
+	for i, arg := range expanded {
+		expanded[i] = replacer.Replace(arg)
+	}
+	return expanded
+}
@@ -4,15 +4,26 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	cacheCmd "github.com/Tifufu/gsim-web-launch/cmd/cache"
 	"github.com/Tifufu/gsim-web-launch/cmd/clear"
 	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/cmd/gsp"
+	"github.com/Tifufu/gsim-web-launch/cmd/logs"
 	"github.com/Tifufu/gsim-web-launch/cmd/registry"
+	sessionCmd "github.com/Tifufu/gsim-web-launch/cmd/session"
+	"github.com/Tifufu/gsim-web-launch/cmd/simulator"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
 	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
 	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+	"github.com/Tifufu/gsim-web-launch/pkg/session"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
@@ -22,16 +33,30 @@ import (
 )
 
 var (
-	serialNumber string
-	platform     string
-	gsCli        *cli.Cli
-	rootCmd      *cobra.Command
+	serialNumber    string
+	platform        robotics.Platform
+	bundleType      string
+	forceUpdate     bool
+	skipUpdate      bool
+	simVersion      string
+	refreshGSP      bool
+	gspFile         string
+	lockFile        string
+	wmAddrSpec      string
+	robotSpecs      []string
+	robots          []robot
+	simFlags        runner.SimulatorOptions
+	launchLock      *session.Lock
+	sessionLogs     *session.LogDir
+	sessionTriggers *runner.Triggers
+	gsCli           *cli.Cli
+	rootCmd         *cobra.Command
 )
 
 type runtimeConfig struct {
-	Winmower  *robotics.WinMower
-	Simulator *robotics.Simulator
-	GSPPaths  *robotics.GSPPaths
+	Robots             []robotRuntime
+	Simulator          *robotics.Simulator
+	StartTriggerBundle string
 }
 
 func newRootCommand(cli *cli.Cli) *cobra.Command {
@@ -44,14 +69,57 @@ func newRootCommand(cli *cli.Cli) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&serialNumber, "serial-number", "s", "", "Serial number of the device")
-	cmd.MarkFlagRequired("serial-number")
+	cmd.Flags().VarP(&platform, "platform", "p", "Platform of the device, detected from the serial number when omitted")
+	cmd.Flags().StringVar(&lockFile, "lock", "", "Launch exactly what a lockfile of an earlier launch resolved to")
+	cmd.Flags().StringArrayVar(&robotSpecs, "robot", nil, "Robot to launch as serial[:platform], repeat to put several robots in one garden")
+	cmd.MarkFlagsOneRequired("serial-number", "lock", "robot")
+	cmd.MarkFlagsMutuallyExclusive("robot", "serial-number")
+	cmd.MarkFlagsMutuallyExclusive("robot", "platform")
+	cmd.MarkFlagsMutuallyExclusive("robot", "lock")
+	cmd.MarkFlagsMutuallyExclusive("lock", "serial-number")
+	cmd.MarkFlagsMutuallyExclusive("lock", "platform")
 
-	cmd.Flags().StringVarP(&platform, "platform", "p", "P25", "Platform of the device")
-	cmd.MarkFlagRequired("platform")
+	cmd.Flags().BoolVar(&forceUpdate, "update", false, "Check for newer WinMower and simulator builds regardless of update policy")
+	cmd.Flags().BoolVar(&skipUpdate, "no-update", false, "Use cached WinMower and simulator builds without checking for updates")
+	cmd.MarkFlagsMutuallyExclusive("update", "no-update")
+
+	cmd.Flags().StringVar(&simVersion, "simulator-version", "", "Simulator build to launch instead of the active one")
+	cmd.Flags().BoolVar(&refreshGSP, "refresh-gsp", false, "Download the Garden Simulator Packet even if a cached one is still fresh")
+	cmd.Flags().StringVar(&gspFile, "gsp-file", "", "Launch with a Garden Simulator Packet from a local zip file or directory")
+	cmd.MarkFlagsMutuallyExclusive("gsp-file", "refresh-gsp")
+	cmd.MarkFlagsMutuallyExclusive("lock", "simulator-version")
+	cmd.MarkFlagsMutuallyExclusive("lock", "gsp-file")
+	cmd.MarkFlagsMutuallyExclusive("robot", "gsp-file")
+	cmd.Flags().StringVar(&bundleType, "bundle-type", "", "WinMower bundle type of the platform to use instead of its newest one")
+	cmd.MarkFlagsMutuallyExclusive("lock", "bundle-type")
+	cmd.MarkFlagsMutuallyExclusive("robot", "bundle-type")
+	cmd.Flags().BoolVar(&simFlags.Log, "simulator-log", false, "Let the simulator write its log")
+	cmd.Flags().Float64Var(&simFlags.TimeScale, "time-scale", 0, "Simulation speed relative to real time")
+	cmd.Flags().IntVar(&simFlags.ScreenWidth, "screen-width", 0, "Width of the simulator window")
+	cmd.Flags().IntVar(&simFlags.ScreenHeight, "screen-height", 0, "Height of the simulator window")
+	cmd.Flags().IntVar(&simFlags.QualityLevel, "quality-level", 0, "Graphics quality level of the simulator")
+	cmd.Flags().StringArrayVar(&simFlags.ExtraArgs, "simulator-arg", nil, "Extra argument passed to the simulator as is, repeat for more")
+	cmd.Flags().StringVar(&wmAddrSpec, "winmower-address", "", `Address WinMower listens on as host:port, or "auto" to pick a free port`)
+	// A lockfile replays the simulator and WinMower arguments it recorded.
+	for _, flag := range []string{"simulator-log", "time-scale", "screen-width", "screen-height", "quality-level", "simulator-arg", "winmower-address"} {
+		cmd.MarkFlagsMutuallyExclusive("lock", flag)
+	}
+
+	cmd.RegisterFlagCompletionFunc("platform", cli.CompletePlatforms)
+	cmd.RegisterFlagCompletionFunc("serial-number", cli.CompleteSerials)
+	cmd.RegisterFlagCompletionFunc("robot", cli.CompleteSerials)
+	cmd.RegisterFlagCompletionFunc("bundle-type", cli.CompleteBundleTypes)
+	cmd.CompletionOptions.DisableDefaultCmd = true
 
 	cmd.AddCommand(
 		registry.RegistryCmd,
 		clear.NewClearCommand(cli),
+		simulator.NewSimulatorCommand(cli),
+		cacheCmd.NewCacheCommand(cli),
+		gsp.NewGSPCommand(cli),
+		sessionCmd.NewSessionCommand(cli),
+		logs.NewLogsCommand(cli),
+		newCompletionCommand(),
 	)
 	return cmd
 }
@@ -69,6 +137,27 @@ func Execute(args []string) {
 		log.Fatalf("Failed to create winmower dir: %s", err)
 	}
 
+	completing := len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd)
+	catalog, err := loadPlatformCatalog(v, completing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	robotics.SetPlatformCatalog(catalog)
+
+	detector, err := platformDetector(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	wmPolicy, err := updatePolicy(v, "winMower")
+	if err != nil {
+		log.Fatal(err)
+	}
+	simPolicy, err := updatePolicy(v, "simulator")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	bRegistry := robotics.NewBundleRegistry(v.GetString("endpoints.bundleStorage"))
 	gsCli = &cli.Cli{
 		Config:            v,
@@ -77,6 +166,27 @@ func Execute(args []string) {
 		WinMowerRegistry:  robotics.NewWinMowerRegistry(wmDir, bRegistry),
 		SimulatorRegistry: robotics.NewSimulatorRegistry(v.GetString("directories.simulator"), bRegistry),
 		GSPRegistry:       robotics.NewGSPRegistry(v.GetString("directories.gardenSimulatorPackets"), v.GetString("endpoints.gardenSimulatorPacket")),
+		PlatformDetector:  detector,
+	}
+	if err := gsCli.GSPRegistry.MigrateLayout(); err != nil {
+		log.Warn("Failed to migrate GSP cache", "err", err)
+	}
+
+	gsCli.WinMowerRegistry.UpdatePolicy = wmPolicy
+	gsCli.SimulatorRegistry.UpdatePolicy = simPolicy
+
+	if maxSize := v.GetString("cache.gsp.maxSize"); maxSize != "" {
+		gsCli.GSPRegistry.MaxSize, err = cache.ParseSize(maxSize)
+		if err != nil {
+			log.Fatal("Invalid cache.gsp.maxSize", "err", err)
+		}
+	}
+	gsCli.GSPRegistry.MaxEntries = v.GetInt("cache.gsp.maxEntries")
+	if ttl := v.GetString("cache.gsp.ttl"); ttl != "" {
+		gsCli.GSPRegistry.TTL, err = time.ParseDuration(ttl)
+		if err != nil {
+			log.Fatal("Invalid cache.gsp.ttl", "err", err)
+		}
 	}
 
 	rootCmd = newRootCommand(gsCli)
@@ -86,6 +196,9 @@ func Execute(args []string) {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if code := sessionExitCode.Load(); code != 0 {
+		os.Exit(int(code))
+	}
 }
 
 func runRootCommand(cli *cli.Cli) {
@@ -99,6 +212,19 @@ func runRootCommand(cli *cli.Cli) {
 	}()
 
 	log.SetLevel(log.InfoLevel)
+	applyUpdateFlags(cli)
+	cli.GSPRegistry.ForceRefresh = refreshGSP
+	cli.WinMowerRegistry.BundleType = bundleType
+
+	if err := resolveRobots(cli); err != nil {
+		log.Error(err)
+		return
+	}
+	simOptions, err := resolveSimulatorOptions(cli, robots[0].Platform)
+	if err != nil {
+		log.Error("Invalid simulator options", "err", err)
+		return
+	}
 
 	var resChan = make(chan runtimeConfig, 1)
 	var errChan = make(chan error, 1)
@@ -115,95 +241,514 @@ func runRootCommand(cli *cli.Cli) {
 		log.Error("Failed to prepare runtime", "err", err)
 		return
 	}
+	defer releaseGSPs(runtime.Robots)
 
 	log.SetLevel(log.DebugLevel)
 
-	wmRunner, err := createWinMowerRunner(cli.Config.GetString("directories.winMowerFileSystems"), runtime.Winmower)
+	terminal := log.Default()
+	sessionLogs = openSessionLogs(cli)
+	if sessionLogs != nil {
+		log.SetDefault(sessionLogs.Logger("launcher", terminal))
+		defer func() {
+			log.SetDefault(terminal)
+			sessionLogs.Close()
+		}()
+		log.Info("Writing session logs", "dir", sessionLogs.Path)
+		if simOptions.Log {
+			simOptions.LogFile = filepath.Join(sessionLogs.Path, "simulator-unity.log")
+		}
+	}
+
+	for _, r := range runtime.Robots {
+		if r.GSPPaths.Stale {
+			log.Warn("Using a stale Garden Simulator Packet, it could not be refreshed", "serial", r.Serial, "dir", r.GSPPaths.Dir)
+		}
+	}
+
+	// The simulator loads the garden of the first robot, connects to the
+	// WinMower of every robot and gets the map of each through robotArgs.
+	first := runtime.Robots[0]
+	simArgs := simulatorArgs(cli, simOptions, first.GSPPaths.Map, runtime.Robots)
+	if launchLock != nil {
+		simArgs = replaySimulatorArgs(launchLock, first.GSPPaths.Map, first.Address, simOptions.LogFile)
+	}
+	if len(runtime.Robots) == 1 {
+		lockPath, err := writeLockfile(cli, runtime, simOptions)
+		if err != nil {
+			log.Warn("Failed to write lockfile", "err", err)
+		} else {
+			log.Info("Wrote lockfile", "path", lockPath)
+		}
+	} else {
+		log.Info("Not writing a lockfile, lockfiles describe a single robot")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sessionTriggers, err = loadTriggers(cli.Config, cancel)
 	if err != nil {
 		log.Error(err)
 		return
 	}
-	log.Info("Starting winmower...")
-	err = wmRunner.Start(context.Background())
+
+	sup := runner.NewSupervisor()
+	defer func() {
+		log.Info("Stopping processes...")
+		if err := sup.Shutdown(); err != nil {
+			log.Error("Failed to stop processes", "err", err)
+		}
+	}()
+
+	wmRestart, err := restartPolicy(cli.Config, "winMower")
 	if err != nil {
-		log.Error("Failed to start winmower", "err", err)
+		log.Error(err)
 		return
 	}
-	defer wmRunner.Stop()
-	time.Sleep(3 * time.Second)
+	filters := make(map[string]*runner.Filter)
+	for name, key := range map[string]string{"winmower": "winMower", "tifconsole": "tifConsole"} {
+		if filters[name], err = logFilter(cli.Config, key); err != nil {
+			log.Error(err)
+			return
+		}
+	}
+	// The console runs while launching so the startup output can be filtered
+	// too. Leaving it stops the launch and all processes.
+	console := make(chan struct{})
+	go func() {
+		defer close(console)
+		runConsole(ctx, filters)
+		cancel()
+	}()
+
+	testRunner, err := createTestBundleRunner(gsCli.Config.GetString("programs.tifConsole"), filters["tifconsole"])
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	var winMowers []string
+	for _, r := range runtime.Robots {
+		r := r
+		wmRunner, err := createWinMowerRunner(cli.Config.GetString("directories.winMowerFileSystems"), r, len(runtime.Robots) == 1, winMowerArgs(cli, r.robot), filters["winmower"])
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		name := r.processName("winmower")
+		sup.Add(name, wmRunner)
+		winMowers = append(winMowers, name)
+
+		probe, timeout, err := readinessProbe(cli.Config, "winMower", r.Address)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if probe != nil {
+			sup.Ready(name, probe, timeout)
+		}
+
+		// A restarted WinMower has lost the state the test bundle set up.
+		sup.Restart(name, wmRestart, func(ctx context.Context) error {
+			log.Info("Running test bundle again after restart...", "serial", r.Serial)
+			return runTestBundle(ctx, sup, r.processName("test-bundle"), testRunner.Bundle(r.GSPPaths.TestBundle, tifArgs(r.robot)...), name)
+		})
+	}
 
-	log.Info("Running test bundle...")
-	testRunner := createTestBundleRunner(gsCli.Config.GetString("programs.tifConsole"))
-	err = testRunner.Run(context.Background(), runtime.GSPPaths.TestBundle, "-tcpAddress", "127.0.0.1:4250")
+	sup.Add("simulator", createSimulatorRunner(runtime.Simulator.Path, simArgs), winMowers...)
+	probe, timeout, err := readinessProbe(cli.Config, "simulator", "")
 	if err != nil {
-		log.Error("Failed to start test bundle", "err", err)
+		log.Error(err)
 		return
 	}
+	if probe != nil {
+		sup.Ready("simulator", probe, timeout)
+	}
+
+	for _, r := range runtime.Robots {
+		log.Info("Starting winmower...", "serial", r.Serial, "address", r.Address)
+		err = sup.Start(ctx, r.processName("winmower"))
+		if err != nil {
+			log.Error("Failed to start winmower", "serial", r.Serial, "err", err)
+			return
+		}
+
+		log.Info("Running test bundle...", "serial", r.Serial)
+		err = runTestBundle(ctx, sup, r.processName("test-bundle"), testRunner.Bundle(r.GSPPaths.TestBundle, tifArgs(r.robot)...), r.processName("winmower"))
+		if err != nil {
+			log.Error("Failed to start test bundle", "serial", r.Serial, "err", err)
+			return
+		}
+	}
 
 	log.Info("Launching simulator...")
-	err = runner.LaunchSimulator(runtime.Simulator.Path, runtime.GSPPaths.Map)
+	err = sup.Start(ctx, "simulator")
 	if err != nil {
 		log.Error("Failed to launch simulator", "err", err)
 		return
 	}
 
-	log.Info("Running start trigger test bundle...")
-	err = testRunner.Run(context.Background(), `C:\Repositories\GardenTVAutoLoader\GardenTVAutoloader\Resources\testscript.zip`, "-tcpAddress", "127.0.0.1:4250")
+	for _, r := range runtime.Robots {
+		log.Info("Running start trigger test bundle...", "serial", r.Serial)
+		err = runTestBundle(ctx, sup, r.processName("start-trigger"), testRunner.Bundle(runtime.StartTriggerBundle, tifArgs(r.robot)...), "simulator")
+		if err != nil {
+			log.Error("Failed to start test bundle", "serial", r.Serial, "err", err)
+			return
+		}
+	}
+
+	<-console
+}
+
+// resolveRobots fills robots from the lockfile, --robot or --serial-number
+// and resolves the platform and WinMower address of each.
+func resolveRobots(cli *cli.Cli) error {
+	robots = nil
+	switch {
+	case lockFile != "":
+		lock, err := session.ReadLock(lockFile)
+		if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+		launchLock = lock
+		robots = append(robots, robot{Serial: lock.SerialNumber, Platform: robotics.Platform(lock.Platform)})
+	case len(robotSpecs) > 0:
+		seen := make(map[string]bool)
+		for _, spec := range robotSpecs {
+			r, err := parseRobot(spec)
+			if err != nil {
+				return err
+			}
+			// Processes are named after the serial, so a repeated one
+			// would replace the WinMower of the first.
+			if seen[r.Serial] {
+				return fmt.Errorf("robot %s is given more than once", r.Serial)
+			}
+			seen[r.Serial] = true
+			robots = append(robots, r)
+		}
+	default:
+		robots = append(robots, robot{Serial: serialNumber, Platform: platform})
+	}
+
+	usedBy := make(map[string]string)
+	for i := range robots {
+		r := &robots[i]
+		if launchLock == nil {
+			if err := resolvePlatform(cli, r); err != nil {
+				return err
+			}
+		}
+
+		address, spec, err := resolveWinMowerAddress(cli, r.Platform.Info())
+		if err != nil {
+			return fmt.Errorf("winmower address of %s is not usable: %w", r.Serial, err)
+		}
+		if other, ok := usedBy[address]; ok {
+			// Robots sharing the default address of their platform get a
+			// free port each, as long as it can be passed on.
+			if wmAddrSpec != "" || cli.Config.GetString("winMower.address") != "" {
+				return fmt.Errorf("robots %s and %s would both use winmower address %s, use --winmower-address auto", other, r.Serial, address)
+			}
+			if !addressArgsConfigured(cli) {
+				return fmt.Errorf("robots %s and %s would both use winmower address %s, configure winMower.addressArgs and simulator.addressArgs so each robot gets its own port", other, r.Serial, address)
+			}
+			for usedBy[address] != "" {
+				if address, err = runner.ResolveAddress(runner.AutoAddress); err != nil {
+					return err
+				}
+			}
+			log.Info("Picked a free winmower address", "serial", r.Serial, "address", address)
+			spec = runner.AutoAddress
+		}
+		usedBy[address] = r.Serial
+		r.Address = address
+		r.AddressSpec = spec
+	}
+
+	if len(robots) > 1 && !slices.ContainsFunc(cli.Config.GetStringSlice("simulator.robotArgs"), func(arg string) bool {
+		return strings.Contains(arg, "{map}")
+	}) {
+		return fmt.Errorf("simulator.robotArgs must pass {map} to launch several robots, otherwise the simulator only loads the garden of %s", robots[0].Serial)
+	}
+	return nil
+}
+
+func addressArgsConfigured(cli *cli.Cli) bool {
+	return len(cli.Config.GetStringSlice("winMower.addressArgs")) > 0 && len(cli.Config.GetStringSlice("simulator.addressArgs")) > 0
+}
+
+// resolveSimulatorOptions layers the simulator flags over the options of the
+// lockfile or, without one, of the platform with config over them.
+func resolveSimulatorOptions(cli *cli.Cli, platform robotics.Platform) (runner.SimulatorOptions, error) {
+	var o runner.SimulatorOptions
+	if launchLock != nil {
+		o = *launchLock.SimulatorOptions
+	} else {
+		var err error
+		if o, err = cli.SimulatorOptions(platform); err != nil {
+			return o, err
+		}
+	}
+
+	flags := rootCmd.Flags()
+	if flags.Changed("simulator-log") {
+		o.Log = simFlags.Log
+	}
+	if flags.Changed("time-scale") {
+		o.TimeScale = simFlags.TimeScale
+	}
+	if flags.Changed("screen-width") {
+		o.ScreenWidth = simFlags.ScreenWidth
+	}
+	if flags.Changed("screen-height") {
+		o.ScreenHeight = simFlags.ScreenHeight
+	}
+	if flags.Changed("quality-level") {
+		o.QualityLevel = simFlags.QualityLevel
+	}
+	o.ExtraArgs = append(o.ExtraArgs, simFlags.ExtraArgs...)
+	// The log file goes into the log dir of this session once it exists.
+	o.LogFile = ""
+	return o, o.Validate()
+}
+
+// resolvePlatform detects the platform of r from its serial number when it
+// was not given and warns when the given one disagrees with the detected one.
+func resolvePlatform(cli *cli.Cli, r *robot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	detected, err := cli.PlatformDetector.Detect(ctx, r.Serial)
 	if err != nil {
-		log.Error("Failed to start test bundle", "err", err)
-		return
+		log.Warn("Failed to detect platform from serial number", "serial", r.Serial, "err", err)
+	}
+	if _, ok := robotics.GetPlatformCatalog().Lookup(detected); detected != "" && !ok {
+		log.Warn("Detected platform is not in the platform catalog, ignoring it", "serial", r.Serial, "detected", detected)
+		detected = ""
 	}
 
-	log.Info("Press enter to exit...")
-	reader := bufio.NewReader(os.Stdin)
-	reader.ReadString('\n')
+	switch {
+	case r.Platform == "" && detected == "":
+		return fmt.Errorf("could not detect the platform of %s, specify it with --platform or as serial:platform", r.Serial)
+	case r.Platform == "":
+		r.Platform = detected
+		log.Info("Detected platform from serial number", "serial", r.Serial, "platform", r.Platform)
+	case detected != "" && r.Platform != detected:
+		log.Warn("Platform differs from the one detected from the serial number", "serial", r.Serial, "platform", r.Platform, "detected", detected)
+	}
+	return nil
 }
 
-func createTestBundleRunner(tifConsolePath string) *runner.TestBundleRunner {
-	logger := log.NewWithOptions(os.Stdout, log.Options{
-		ReportCaller:    false,
-		ReportTimestamp: true,
-		TimeFormat:      time.TimeOnly,
-		Prefix:          "TifConsole.Auto",
-	})
-	tifColor := lipgloss.Color("#3b82f6")
-	style := log.DefaultStyles()
-	style.Prefix = lipgloss.NewStyle().Foreground(tifColor)
-	style.Timestamp = lipgloss.NewStyle().
-		BorderStyle(lipgloss.ThickBorder()).
-		BorderLeftBackground(tifColor).
-		BorderLeftForeground(tifColor).
-		BorderLeft(true).
-		PaddingLeft(1)
-	logger.SetStyles(style)
-	testLogger := runner.NewTifConsoleLogger(logger)
-	testRunner := runner.NewTestBundleRunner(tifConsolePath, testLogger)
-	return testRunner
+// resolveWinMowerAddress picks the WinMower address from the lockfile or
+// --winmower-address, then config, then the platform, and makes sure it can
+// be listened on. It returns the address and what it was resolved from.
+func resolveWinMowerAddress(cli *cli.Cli, info *robotics.PlatformInfo) (address, spec string, err error) {
+	spec = wmAddrSpec
+	if launchLock != nil {
+		spec = launchLock.WinMowerAddress
+	}
+	if spec == "" {
+		spec = cli.Config.GetString("winMower.address")
+	}
+	if spec == "" {
+		spec = info.DefaultWinMowerAddress()
+	}
+
+	address, err = runner.ResolveAddress(spec)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Only TifConsole is always told the address, WinMower and the simulator
+	// stay on the built-in one unless their address arguments are configured.
+	// A lockfile replays the arguments that were checked when it was written.
+	if launchLock == nil && address != info.BuiltinWinMowerAddress() && !addressArgsConfigured(cli) {
+		return "", "", fmt.Errorf("winMower.addressArgs and simulator.addressArgs must be configured to use %s instead of the built-in address %s", address, info.BuiltinWinMowerAddress())
+	}
+	return address, spec, nil
+}
+
+// simulatorArgs returns the simulator arguments that load the map at mapPath
+// and connect to and place every robot.
+func simulatorArgs(cli *cli.Cli, o runner.SimulatorOptions, mapPath string, robots []robotRuntime) []string {
+	args := o.Args(mapPath)
+	for _, r := range robots {
+		args = append(args, runner.ExpandArgs(cli.Config.GetStringSlice("simulator.addressArgs"), r.Address)...)
+		args = append(args, r.expandArgs(cli.Config.GetStringSlice("simulator.robotArgs"))...)
+	}
+	return args
+}
+
+// tifArgs returns the TifConsole arguments that connect it to the WinMower of
+// r, or the ones recorded in the lockfile.
+func tifArgs(r robot) []string {
+	if launchLock != nil {
+		return runner.ExpandArgs(launchLock.TestBundleArgs, r.Address)
+	}
+	return []string{"-tcpAddress", r.Address}
+}
+
+// winMowerArgs returns the arguments that tell the WinMower of r its
+// address, or the ones recorded in the lockfile.
+func winMowerArgs(cli *cli.Cli, r robot) []string {
+	if launchLock != nil {
+		return runner.ExpandArgs(launchLock.WinMowerArgs, r.Address)
+	}
+	return runner.ExpandArgs(cli.Config.GetStringSlice("winMower.addressArgs"), r.Address)
+}
+
+func applyUpdateFlags(cli *cli.Cli) {
+	switch {
+	case forceUpdate:
+		cli.WinMowerRegistry.UpdatePolicy = robotics.UpdatePolicy{Mode: robotics.UpdateAlways}
+		cli.SimulatorRegistry.UpdatePolicy = robotics.UpdatePolicy{Mode: robotics.UpdateAlways}
+	case skipUpdate:
+		cli.WinMowerRegistry.UpdatePolicy = robotics.UpdatePolicy{Mode: robotics.UpdateNever}
+		cli.SimulatorRegistry.UpdatePolicy = robotics.UpdatePolicy{Mode: robotics.UpdateNever}
+	}
 }
 
-func createWinMowerRunner(wmFsCacheDir string, winMower *robotics.WinMower) (*runner.WinMowerRunner, error) {
-	wmDir := filepath.Join(wmFsCacheDir, platform)
+func createTestBundleRunner(tifConsolePath string, filter *runner.Filter) (*runner.TestBundleRunner, error) {
+	parser, err := runner.NewTestResultParser(
+		gsCli.Config.GetString("tifConsole.results.casePattern"),
+		gsCli.Config.GetString("tifConsole.results.stepPattern"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tifConsole.results: %w", err)
+	}
+	logger := newProcessLogger("TifConsole.Auto", "#3b82f6")
+	var output io.Writer = runner.NewTifConsoleLogger(logger, sessionRecorder("tifconsole"), filter)
+	if watchers := outputWatchers("tifconsole"); len(watchers) > 0 {
+		output = io.MultiWriter(append([]io.Writer{output}, watchers...)...)
+	}
+	testRunner := runner.NewTestBundleRunner(tifConsolePath, output, parser)
+	return testRunner, nil
+}
+
+func createWinMowerRunner(wmFsCacheDir string, r robotRuntime, alone bool, args []string, filter *runner.Filter) (*runner.WinMowerRunner, error) {
+	wmDir := filepath.Join(wmFsCacheDir, r.fsDirName(alone))
 	err := os.MkdirAll(wmDir, 0755)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create winmower dir: %w", err)
 	}
-	logger := log.NewWithOptions(os.Stdout, log.Options{
+	source := "winmower"
+	if !alone {
+		source = r.processName("winmower")
+	}
+	parser, err := runner.NewWinMowerLogParser(gsCli.Config.GetString("winMower.logPattern"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid winMower.logPattern: %w", err)
+	}
+	logger := newProcessLogger(winMowerPrefix(r.robot, alone), "#8b5cf6")
+	wmLogger := runner.NewWinMowerLogger(logger, sessionRecorder(source), parser, filter)
+	wmRunner := runner.NewWinMowerRunner(wmDir, r.Winmower.Path, args, wmLogger)
+	for _, w := range outputWatchers(source) {
+		wmRunner.Watch(w)
+	}
+	return wmRunner, nil
+}
+
+func createSimulatorRunner(simPath string, args []string) *runner.SimulatorRunner {
+	logger := sessionLogger("simulator", newProcessLogger("GardenSimulator", "#10b981"))
+	simRunner := runner.NewSimulatorRunner(simPath, args, runner.NewSimulatorLogger(logger))
+	for _, w := range outputWatchers("simulator") {
+		simRunner.Watch(w)
+	}
+	return simRunner
+}
+
+// openSessionLogs creates the log dir of this launch and prunes old ones. The
+// launch goes on without session logs when that fails.
+func openSessionLogs(cli *cli.Cli) *session.LogDir {
+	root := cli.Config.GetString("directories.logs")
+	var serials []string
+	for _, r := range robots {
+		serials = append(serials, r.Serial)
+	}
+	dir, err := session.CreateLogDir(root, strings.Join(serials, "_"), time.Now())
+	if err != nil {
+		log.Warn("Failed to create session log dir, logging to the terminal only", "err", err)
+		return nil
+	}
+
+	maxAge, err := time.ParseDuration(cli.Config.GetString("logs.retention.maxAge"))
+	if err != nil {
+		log.Warn("Invalid logs.retention.maxAge, keeping old session logs", "err", err)
+		return dir
+	}
+	removed, err := session.PruneLogDirs(root, cli.Config.GetInt("logs.retention.maxSessions"), maxAge, dir.Path)
+	if err != nil {
+		log.Warn("Failed to remove old session logs", "err", err)
+	}
+	if len(removed) > 0 {
+		log.Debug("Removed old session logs", "count", len(removed))
+	}
+	return dir
+}
+
+// sessionLogger records the entries of terminal in the session logs as
+// coming from source, when there are session logs.
+func sessionLogger(source string, terminal *log.Logger) *log.Logger {
+	if sessionLogs == nil {
+		return terminal
+	}
+	return sessionLogs.Logger(source, terminal)
+}
+
+// sessionRecorder returns a logger that only records entries in the session
+// logs as coming from source, or nil when there are no session logs.
+func sessionRecorder(source string) *log.Logger {
+	if sessionLogs == nil {
+		return nil
+	}
+	return sessionLogs.Logger(source, nil)
+}
+
+// outputWatchers returns what the unprocessed output of source is written to
+// besides its logger: the raw session log and the triggers.
+func outputWatchers(source string) []io.Writer {
+	var watchers []io.Writer
+	if sessionLogs != nil {
+		raw, err := sessionLogs.Raw(source)
+		if err != nil {
+			log.Warn("Failed to create raw log", "source", source, "err", err)
+		} else {
+			watchers = append(watchers, raw)
+		}
+	}
+	if sessionTriggers != nil && !sessionTriggers.Empty() {
+		watchers = append(watchers, sessionTriggers.Writer(source))
+	}
+	return watchers
+}
+
+// newProcessLogger returns a logger for the output of a process, marked with
+// prefix and color.
+func newProcessLogger(prefix, color string) *log.Logger {
+	logger := log.NewWithOptions(terminalOut, log.Options{
 		ReportCaller:    false,
 		ReportTimestamp: true,
 		TimeFormat:      time.TimeOnly,
-		Prefix:          "WinMower",
+		Prefix:          prefix,
 	})
-	wmColor := lipgloss.Color("#8b5cf6")
+	c := lipgloss.Color(color)
 	style := log.DefaultStyles()
-	style.Prefix = lipgloss.NewStyle().Foreground(wmColor)
+	style.Prefix = lipgloss.NewStyle().Foreground(c)
 	style.Timestamp = lipgloss.NewStyle().
 		BorderStyle(lipgloss.ThickBorder()).
-		BorderLeftBackground(wmColor).
-		BorderLeftForeground(wmColor).
+		BorderLeftBackground(c).
+		BorderLeftForeground(c).
 		BorderLeft(true).
 		PaddingLeft(1)
 	logger.SetStyles(style)
-	wmLogger := runner.NewWinMowerLogger(logger)
-	wmRunner := runner.NewWinMowerRunner(wmDir, winMower.Path, wmLogger)
-	return wmRunner, nil
+	return logger
+}
+
+func winMowerPrefix(r robot, alone bool) string {
+	if alone {
+		return "WinMower"
+	}
+	return "WinMower " + r.Serial
 }
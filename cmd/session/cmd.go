@@ -0,0 +1,24 @@
+package session
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewSessionCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Export and import reproducible sessions",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newExportCommand(gsCli),
+		newImportCommand(gsCli),
+	)
+
+	return cmd
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/Tifufu/gsim-web-launch/pkg/session"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand(gsCli *cli.Cli) *cobra.Command {
+	var platform robotics.Platform
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a launch of a device into one archive",
+		Long: `Export the lockfile of a launch together with its GSP, start trigger bundle
+and the WinMower filesystem of the device into one archive that can be
+restored with session import. The newest lockfile of the device is used
+unless --lock is given.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			serialNumber, _ := cmd.Flags().GetString("serial-number")
+			lockPath, _ := cmd.Flags().GetString("lock")
+			output, _ := cmd.Flags().GetString("output")
+
+			if lockPath == "" {
+				var err error
+				lockPath, err = session.LatestLock(gsCli.Config.GetString("directories.locks"), serialNumber, string(platform))
+				if err != nil {
+					log.Error("Failed to find lockfile", "err", err)
+					return
+				}
+				if lockPath == "" {
+					log.Error("No lockfile, launch the device once to write one", "serial", serialNumber, "platform", platform)
+					return
+				}
+			}
+			lock, err := session.ReadLock(lockPath)
+			if err != nil {
+				log.Error("Failed to read lockfile", "err", err)
+				return
+			}
+
+			gsp, err := gsCli.GSPRegistry.GetGSPFromCache(lock.SerialNumber, lock.Platform)
+			if err != nil {
+				log.Error("Failed to read cached GSP", "err", err)
+				return
+			}
+			if gsp == nil {
+				log.Error("No cached GSP", "serial", lock.SerialNumber, "platform", lock.Platform)
+				return
+			}
+			sums, err := cache.HashDir(gsp.Dir)
+			if err != nil {
+				log.Error("Failed to hash GSP", "err", err)
+				return
+			}
+			if hash := cache.Digest(sums); hash != lock.GSPHash {
+				log.Error("The cached GSP changed since the locked launch", "serial", lock.SerialNumber, "hash", hash, "locked", lock.GSPHash)
+				return
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("%s-%s-%s.gsim-session.zip", lock.SerialNumber, lock.Platform, time.Now().Format("20060102-150405"))
+			}
+			manifest := &session.Manifest{
+				CreatedAt:    time.Now(),
+				SerialNumber: lock.SerialNumber,
+				Platform:     lock.Platform,
+				WinMower:     lock.WinMower,
+				Simulator:    lock.Simulator,
+				Lock:         lock,
+			}
+			wmFsDir := filepath.Join(gsCli.Config.GetString("directories.winMowerFileSystems"), lock.Platform)
+
+			if err := session.Export(output, manifest, gsp.Dir, wmFsDir); err != nil {
+				log.Error("Failed to export session", "err", err)
+				return
+			}
+			log.Info("Exported session", "file", output, "lock", lockPath)
+		},
+	}
+	cmd.Flags().StringP("serial-number", "s", "", "Serial number of the device")
+	cmd.RegisterFlagCompletionFunc("serial-number", gsCli.CompleteSerials)
+	cmd.Flags().VarP(&platform, "platform", "p", "Platform of the device")
+	cmd.RegisterFlagCompletionFunc("platform", gsCli.CompletePlatforms)
+	cmd.Flags().String("lock", "", "Lockfile of the launch to export instead of the newest one of the device")
+	cmd.MarkFlagsRequiredTogether("serial-number", "platform")
+	cmd.MarkFlagsOneRequired("serial-number", "lock")
+	cmd.MarkFlagsMutuallyExclusive("serial-number", "lock")
+	cmd.MarkFlagsMutuallyExclusive("platform", "lock")
+	cmd.Flags().StringP("output", "o", "", "Archive to write, defaults to <serial>-<platform>-<time>.gsim-session.zip")
+	return cmd
+}
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/Tifufu/gsim-web-launch/pkg/ext"
+	"github.com/Tifufu/gsim-web-launch/pkg/robotics"
+	"github.com/Tifufu/gsim-web-launch/pkg/session"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Restore a session exported with session export",
+		Long: `Restore a session exported with session export: register its GSP, fetch the
+exact WinMower and simulator builds, activate that simulator, restore the
+WinMower filesystem and write its lockfile, which launches the same setup
+with --lock.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool("force")
+
+			archive, err := session.Open(args[0])
+			if err != nil {
+				log.Error("Failed to open session", "err", err)
+				return
+			}
+			defer archive.Close()
+			m := archive.Manifest
+
+			wmFsDir := filepath.Join(gsCli.Config.GetString("directories.winMowerFileSystems"), m.Platform)
+			if _, err := os.Stat(wmFsDir); err == nil && archive.WinMowerFSDir() != "" && !force {
+				log.Error("A winmower filesystem already exists, use --force to replace it", "dir", wmFsDir)
+				return
+			}
+
+			log.Info("Importing GSP...", "serial", m.SerialNumber, "platform", m.Platform)
+			if _, err := gsCli.GSPRegistry.ImportGSP(archive.GSPDir(), m.SerialNumber, m.Platform); err != nil {
+				log.Error("Failed to import GSP", "err", err)
+				return
+			}
+
+			log.Info("Fetching winmower...", "build", m.WinMower.BuildId)
+			_, err = gsCli.WinMowerRegistry.GetWinMowerBuild(robotics.Platform(m.Platform), m.WinMower.BundleType, m.WinMower.BuildId, cmd.Context())
+			if err != nil {
+				log.Error("Failed to get winmower", "build", m.WinMower.BuildId, "err", err)
+				return
+			}
+
+			log.Info("Fetching simulator...", "build", m.Simulator.BuildId)
+			if _, err := gsCli.SimulatorRegistry.GetSimulatorVersion(cmd.Context(), m.Simulator.BuildId); err != nil {
+				log.Error("Failed to get simulator", "build", m.Simulator.BuildId, "err", err)
+				return
+			}
+			if err := gsCli.SimulatorRegistry.UseSimulator(m.Simulator.BuildId); err != nil {
+				log.Error("Failed to activate simulator", "err", err)
+				return
+			}
+
+			if src := archive.WinMowerFSDir(); src != "" {
+				log.Info("Restoring winmower filesystem...", "dir", wmFsDir)
+				if err := os.RemoveAll(wmFsDir); err != nil {
+					log.Error("Failed to remove winmower filesystem", "err", err)
+					return
+				}
+				if err := ext.CopyDir(src, wmFsDir); err != nil {
+					log.Error("Failed to restore winmower filesystem", "err", err)
+					return
+				}
+			}
+
+			lockPath, err := writeLock(gsCli, archive)
+			if err != nil {
+				log.Error("Failed to write lockfile", "err", err)
+				return
+			}
+
+			log.Info("Session restored", "lock", lockPath)
+			fmt.Printf("Launch it with: gsim-web-launch --lock %s\n", lockPath)
+		},
+	}
+	cmd.Flags().Bool("force", false, "Replace an existing winmower filesystem for the platform")
+	return cmd
+}
+
+// writeLock writes the lockfile of archive to the lock directory, with its
+// start trigger bundle next to it.
+func writeLock(gsCli *cli.Cli, archive *session.Archive) (string, error) {
+	dir := gsCli.Config.GetString("directories.locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	lock := *archive.Manifest.Lock
+	path := filepath.Join(dir, session.LockName(lock.SerialNumber, lock.Platform, time.Now()))
+
+	lock.StartTriggerBundle = strings.TrimSuffix(path, ".lock.json") + ".start-trigger.zip"
+	if err := ext.CopyFile(archive.StartTriggerBundle(), lock.StartTriggerBundle); err != nil {
+		return "", fmt.Errorf("failed to restore start trigger bundle: %w", err)
+	}
+	return path, session.WriteLock(path, &lock)
+}
@@ -0,0 +1,25 @@
+package simulator
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/spf13/cobra"
+)
+
+func NewSimulatorCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulator",
+		Short: "Manage cached Garden Simulator versions",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newListCommand(gsCli),
+		newUseCommand(gsCli),
+		newRemoveCommand(gsCli),
+	)
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newListCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cached simulator versions",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			sims, err := gsCli.SimulatorRegistry.ListSimulators()
+			if err != nil {
+				log.Error("Failed to list simulators", "err", err)
+				return
+			}
+			active, err := gsCli.SimulatorRegistry.ActiveVersion()
+			if err != nil {
+				log.Error("Failed to read active simulator", "err", err)
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "\tBUILD\tDOWNLOADED\tPATH")
+			for _, sim := range sims {
+				marker := ""
+				if sim.BuildId == active {
+					marker = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, sim.BuildId, sim.DownloadedAt.Format(time.DateTime), sim.Path)
+			}
+			w.Flush()
+		},
+	}
+	return cmd
+}
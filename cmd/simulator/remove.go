@@ -0,0 +1,25 @@
+package simulator
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newRemoveCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove <build>",
+		Short: "Remove a cached simulator version",
+		Long:  ``,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := gsCli.SimulatorRegistry.RemoveSimulator(args[0])
+			if err != nil {
+				log.Error("Failed to remove simulator", "build", args[0], "err", err)
+				return
+			}
+			log.Info("Removed simulator", "build", args[0])
+		},
+	}
+	return cmd
+}
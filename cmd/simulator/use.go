@@ -0,0 +1,36 @@
+package simulator
+
+import (
+	"github.com/Tifufu/gsim-web-launch/cmd/cli"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+)
+
+func newUseCommand(gsCli *cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use <build>",
+		Short: "Make a cached simulator version the active one",
+		Long:  `Make a cached simulator version the active one. Use --download to fetch it first if it is not cached.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			buildId := args[0]
+			download, _ := cmd.Flags().GetBool("download")
+			if download {
+				_, err := gsCli.SimulatorRegistry.GetSimulatorVersion(cmd.Context(), buildId)
+				if err != nil {
+					log.Error("Failed to get simulator", "build", buildId, "err", err)
+					return
+				}
+			}
+
+			err := gsCli.SimulatorRegistry.UseSimulator(buildId)
+			if err != nil {
+				log.Error("Failed to use simulator", "build", buildId, "err", err)
+				return
+			}
+			log.Info("Active simulator set", "build", buildId)
+		},
+	}
+	cmd.Flags().Bool("download", false, "Download the version if it is not cached")
+	return cmd
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+)
+
+// maxPausedOutput is how much process output is held back while the log
+// view is paused. Output beyond it is dropped.
+const maxPausedOutput = 8 << 20
+
+var (
+	// terminalOut is where process output is shown. Launcher messages are
+	// not paused so alerts stay visible.
+	terminalOut = &pauseWriter{w: os.Stdout}
+	// sessionExitCode is set by a stop trigger.
+	sessionExitCode atomic.Int32
+	// runningHooks holds the triggers whose hook is running.
+	runningHooks sync.Map
+
+	alertStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#ffffff")).Background(lipgloss.Color("#dc2626"))
+)
+
+// pauseWriter holds back what is written to it while it is paused.
+type pauseWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	paused  bool
+	held    bytes.Buffer
+	dropped int
+}
+
+func (p *pauseWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return p.w.Write(b)
+	}
+	if p.held.Len()+len(b) > maxPausedOutput {
+		p.dropped += len(b)
+	} else {
+		p.held.Write(b)
+	}
+	return len(b), nil
+}
+
+// Pause holds back output and reports whether it was not paused already.
+func (p *pauseWriter) Pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wasPaused := p.paused
+	p.paused = true
+	return !wasPaused
+}
+
+// Resume writes the held back output and lets output through again.
+func (p *pauseWriter) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return
+	}
+	p.w.Write(p.held.Bytes())
+	if p.dropped > 0 {
+		fmt.Fprintf(p.w, "... %d bytes of output were dropped while paused\n", p.dropped)
+	}
+	p.held.Reset()
+	p.dropped = 0
+	p.paused = false
+}
+
+func loadTriggers(v *viper.Viper, cancel context.CancelFunc) (*runner.Triggers, error) {
+	var rules []runner.TriggerRule
+	if err := v.UnmarshalKey("triggers", &rules); err != nil {
+		return nil, fmt.Errorf("invalid triggers: %w", err)
+	}
+	triggers, err := runner.NewTriggers(rules, func(m runner.TriggerMatch) {
+		fireTrigger(m, cancel)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid triggers: %w", err)
+	}
+	return triggers, nil
+}
+
+func fireTrigger(m runner.TriggerMatch, cancel context.CancelFunc) {
+	name := m.Rule.Name
+	for _, action := range m.Rule.Actions {
+		switch action {
+		case runner.TriggerAlert:
+			fmt.Fprint(os.Stderr, "\a")
+			log.Warn(alertStyle.Render(" "+name+" "), "source", m.Source, "line", m.Line)
+		case runner.TriggerPause:
+			if terminalOut.Pause() {
+				log.Warn("Log view paused, type resume to continue", "trigger", name)
+			}
+		case runner.TriggerMarker:
+			log.Info("Marker", "trigger", name, "source", m.Source, "line", m.Line)
+		case runner.TriggerHook:
+			// One hook per trigger at a time, so a burst of matches does not
+			// start a shell for each.
+			if _, running := runningHooks.LoadOrStore(m.Rule, true); running {
+				log.Debug("Trigger hook still running, skipping it", "trigger", name)
+				break
+			}
+			go func() {
+				defer runningHooks.Delete(m.Rule)
+				runHook(m)
+			}()
+		case runner.TriggerStop:
+			log.Error("Stopping session", "trigger", name, "source", m.Source, "line", m.Line, "exitCode", m.Rule.ExitCode)
+			sessionExitCode.CompareAndSwap(0, int32(m.Rule.ExitCode))
+			cancel()
+		}
+	}
+}
+
+// runHook runs the shell command of a trigger with the match in its
+// environment.
+func runHook(m runner.TriggerMatch) {
+	cmd := exec.Command("cmd", "/C", m.Rule.Hook)
+	cmd.Env = append(os.Environ(),
+		"GSIM_TRIGGER="+m.Rule.Name,
+		"GSIM_SOURCE="+m.Source,
+		"GSIM_LINE="+m.Line,
+	)
+	if sessionLogs != nil {
+		cmd.Env = append(cmd.Env, "GSIM_LOG_DIR="+sessionLogs.Path)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Error("Trigger hook failed", "trigger", m.Rule.Name, "err", err, "output", string(out))
+		return
+	}
+	log.Debug("Trigger hook ran", "trigger", m.Rule.Name, "output", string(out))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"net/url"
 	"os"
 	"strings"
 
@@ -9,6 +10,17 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// urlFlags are the query parameters of a gsim-web-launch: URL that are passed
+// on as root command flags of the same name.
+var urlFlags = []string{
+	"refresh-gsp",
+	"simulator-log",
+	"time-scale",
+	"screen-width",
+	"screen-height",
+	"quality-level",
+}
+
 func init() {
 	if err := godotenv.Load(`D:\Projects\_work\_pocs\gsim-web-launch\bin\.env`, "./.env"); err != nil {
 		log.Error("could not load environment file")
@@ -16,9 +28,15 @@ func init() {
 }
 
 func main() {
-	if strings.HasPrefix(os.Args[1], "gsim-web-launch:") {
-		input := strings.Split(os.Args[1], ":")[1]
-		parts := strings.Split(input, "/")
+	if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "gsim-web-launch:") {
+		u, err := url.Parse(os.Args[1])
+		if err != nil {
+			log.Fatal("Invalid launch URL", "url", os.Args[1], "err", err)
+		}
+		parts := strings.Split(u.Opaque, "/")
+		if len(parts) < 2 {
+			log.Fatal("Launch URL must be of the form gsim-web-launch:<serial>/<platform>", "url", os.Args[1])
+		}
 		serial := parts[0]
 		platform := parts[1]
 		args := []string{
@@ -27,9 +45,25 @@ func main() {
 			"-p",
 			platform,
 		}
+		args = append(args, queryFlags(u.Query())...)
 		os.Args = append(os.Args[:1], args...)
 	}
 
 	log.Debug(os.Args)
 	cmd.Execute(os.Args[1:])
 }
+
+func queryFlags(query url.Values) []string {
+	var args []string
+	for _, name := range urlFlags {
+		if !query.Has(name) {
+			continue
+		}
+		if value := query.Get(name); value != "" {
+			args = append(args, "--"+name+"="+value)
+		} else {
+			args = append(args, "--"+name)
+		}
+	}
+	return args
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KindWinMower   = "winmower"
+	KindSimulator  = "simulator"
+	KindGSP        = "gsp"
+	KindWinMowerFS = "winmower-fs"
+)
+
+// Entry is one directory in a cache root, e.g. one simulator version or one
+// GSP serial number.
+type Entry struct {
+	Kind     string
+	Key      string
+	Version  string
+	Dir      string
+	Size     int64
+	LastUsed time.Time
+	Manifest *Manifest
+}
+
+// ListEntries returns an entry for every directory depth levels below root.
+// The key of an entry is its slash separated path relative to root. Hidden
+// directories, such as in-progress downloads, are skipped.
+func ListEntries(kind, root string, depth int) ([]Entry, error) {
+	return listEntries(kind, root, "", depth)
+}
+
+func listEntries(kind, dir, prefix string, depth int) ([]Entry, error) {
+	dirents, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, d := range dirents {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		key := prefix + d.Name()
+		path := filepath.Join(dir, d.Name())
+		if depth > 1 {
+			nested, err := listEntries(kind, path, key+"/", depth-1)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, nested...)
+			continue
+		}
+
+		e, err := readEntry(kind, key, path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// RemoveEntry removes the entry and, for nested entries, its parent
+// directory once that is empty.
+func RemoveEntry(e Entry) error {
+	if err := os.RemoveAll(e.Dir); err != nil {
+		return err
+	}
+	if strings.Contains(e.Key, "/") {
+		// Fails harmlessly while the parent still holds other entries.
+		os.Remove(filepath.Dir(e.Dir))
+	}
+	return nil
+}
+
+func readEntry(kind, key, dir string) (Entry, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	size, err := DirSize(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{
+		Kind:     kind,
+		Key:      key,
+		Dir:      dir,
+		Size:     size,
+		Manifest: m,
+	}
+	switch {
+	case m != nil && !m.LastUsed.IsZero():
+		e.LastUsed = m.LastUsed
+	case m != nil:
+		e.LastUsed = m.DownloadedAt
+	default:
+		info, err := os.Stat(dir)
+		if err != nil {
+			return Entry{}, err
+		}
+		e.LastUsed = info.ModTime()
+	}
+	if m != nil {
+		e.Version = m.BuildId
+	}
+	return e, nil
+}
+
+// SortByLastUsed orders entries least recently used first.
+func SortByLastUsed(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+}
+
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses sizes such as "500MB" or "10GB". Plain numbers are bytes.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	for _, u := range sizeUnits {
+		if num, ok := strings.CutSuffix(str, u.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid size: %s", s)
+			}
+			return int64(n * float64(u.bytes)), nil
+		}
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return n, nil
+}
+
+func FormatSize(size int64) string {
+	for _, u := range sizeUnits {
+		if size >= u.bytes && u.bytes > 1 {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(u.bytes), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type Mismatch struct {
+	Path   string
+	Reason string
+}
+
+// HashDir hashes every file below dir except the cache metadata.
+func HashDir(dir string) (map[string]string, error) {
+	sums := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (filepath.Dir(path) == filepath.Clean(dir) && IsMetadata(d.Name())) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// Verify re-hashes the files recorded in the manifest of dir. Files that were
+// added after the entry was recorded are not reported.
+func Verify(dir string, m *Manifest) ([]Mismatch, error) {
+	paths := make([]string, 0, len(m.Checksums))
+	for p := range m.Checksums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var mismatches []Mismatch
+	for _, p := range paths {
+		sum, err := HashFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if os.IsNotExist(err) {
+			mismatches = append(mismatches, Mismatch{Path: p, Reason: "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sum != m.Checksums[p] {
+			mismatches = append(mismatches, Mismatch{Path: p, Reason: "checksum mismatch"})
+		}
+	}
+	return mismatches, nil
+}
+
+// HashFile returns the hex encoded SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Digest combines the checksums of a directory, as returned by HashDir, into
+// a single hex encoded SHA-256.
+func Digest(sums map[string]string) string {
+	paths := make([]string, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		io.WriteString(h, p+" "+sums[p]+"\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
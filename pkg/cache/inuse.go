@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const inUsePrefix = ".gsim-in-use-"
+
+// MarkInUse records that the current process is using the entry in dir so
+// that it is not evicted. The marker is removed by calling release.
+func MarkInUse(dir string) (release func(), err error) {
+	path := filepath.Join(dir, inUsePrefix+strconv.Itoa(os.Getpid()))
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to mark %s in use: %w", dir, err)
+	}
+	return func() {
+		os.Remove(path)
+	}, nil
+}
+
+// InUse reports whether a running process has marked dir in use. Markers
+// left behind by processes that are no longer running are removed.
+func InUse(dir string) bool {
+	markers, err := filepath.Glob(filepath.Join(dir, inUsePrefix+"*"))
+	if err != nil {
+		return false
+	}
+
+	inUse := false
+	for _, m := range markers {
+		pid, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(m), inUsePrefix))
+		if err == nil && processAlive(pid) {
+			inUse = true
+			continue
+		}
+		os.Remove(m)
+	}
+	return inUse
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess opens a handle and fails for exited processes.
+	if runtime.GOOS == "windows" {
+		p.Release()
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// IsMetadata reports whether name is one of the files this package keeps at
+// the root of an entry directory.
+func IsMetadata(name string) bool {
+	return name == manifestFile || strings.HasPrefix(name, inUsePrefix)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const manifestFile = ".gsim-cache.json"
+
+// Manifest describes what a cache directory holds and is stored inside it.
+type Manifest struct {
+	Kind         string    `json:"kind"`
+	Key          string    `json:"key"`
+	BuildId      string    `json:"buildId,omitempty"`
+	BundleType   string    `json:"bundleType,omitempty"`
+	Platform     string    `json:"platform,omitempty"`
+	DownloadedAt time.Time `json:"downloadedAt"`
+	CheckedAt    time.Time `json:"checkedAt"`
+	LastUsed     time.Time `json:"lastUsed"`
+	// ETag and LastModified are the validators the server sent with the
+	// entry, used to make conditional requests when refreshing it.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Imported entries were added from local files rather than downloaded.
+	Imported bool `json:"imported,omitempty"`
+	// Checksums maps slash separated paths relative to the entry directory
+	// to their hex encoded SHA-256.
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+// ReadManifest returns nil without an error when dir has no manifest.
+func ReadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("error unmarshalling cache manifest in %s: %v", dir, err)
+	}
+	return &m, nil
+}
+
+func WriteManifest(dir string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFile), b, 0644)
+}
+
+// Touch records that the entry in dir was just used. Entries without a
+// manifest are left alone.
+func Touch(dir string) error {
+	m, err := ReadManifest(dir)
+	if err != nil || m == nil {
+		return err
+	}
+	m.LastUsed = time.Now()
+	return WriteManifest(dir, m)
+}
@@ -2,6 +2,7 @@ package ext
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,40 +12,54 @@ import (
 	"strings"
 )
 
+// ErrNotModified is returned when a conditional request is answered with 304.
+var ErrNotModified = errors.New("not modified")
+
 func DownloadAndUnpack(req *http.Request, dest string) error {
+	_, err := DownloadAndUnpackWithHeaders(req, dest)
+	return err
+}
+
+// DownloadAndUnpackWithHeaders works like DownloadAndUnpack but also returns
+// the response headers so callers can keep validators such as ETag.
+func DownloadAndUnpackWithHeaders(req *http.Request, dest string) (http.Header, error) {
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Println(err)
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return resp.Header, ErrNotModified
+	}
+
 	if resp.StatusCode > 299 {
 		b, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("response failed with %s", resp.Status)
+			return nil, fmt.Errorf("response failed with %s", resp.Status)
 		}
-		return fmt.Errorf("response failed with %s, %s", resp.Status, string(b))
+		return nil, fmt.Errorf("response failed with %s, %s", resp.Status, string(b))
 	}
 
 	tmpFile, err := os.CreateTemp(os.TempDir(), "winmower_*.zip")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer os.Remove(tmpFile.Name())
 	defer tmpFile.Close()
 
 	_, err = io.Copy(tmpFile, resp.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = Unzip(tmpFile.Name(), dest)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return resp.Header, nil
 }
 
 func Unzip(zipFile string, dest string) error {
@@ -0,0 +1,40 @@
+package ext
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ZipDir adds the files below src to zw under prefix. Files for which skip
+// returns true are left out; skip may be nil.
+func ZipDir(zw *zip.Writer, src, prefix string, skip func(name string) bool) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (skip != nil && skip(d.Name())) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(path.Join(prefix, filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
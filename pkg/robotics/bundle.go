@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 )
 
+const releaseSearchCount = 50
+
 type BundleRegistry struct {
 	baseUrl string
 }
@@ -64,7 +65,35 @@ func (r *BundleRegistry) FetchBundleTypes(ctx context.Context) ([]BundleType, er
 }
 
 func (r *BundleRegistry) FetchLatestRelease(ctx context.Context, bundleType string) (*Build, error) {
-	url := fmt.Sprintf("%s/bundles/indexes/%s?count=1", r.baseUrl, bundleType)
+	builds, err := r.FetchReleases(ctx, bundleType, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(builds) == 0 {
+		return nil, errors.New("no builds found")
+	}
+
+	return &builds[0], nil
+}
+
+// FetchRelease looks for a specific build among the most recent releases of bundleType.
+func (r *BundleRegistry) FetchRelease(ctx context.Context, bundleType, buildId string) (*Build, error) {
+	builds, err := r.FetchReleases(ctx, bundleType, releaseSearchCount)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range builds {
+		if b.Id == buildId {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("build %s of %s not found in the latest %d releases", buildId, bundleType, releaseSearchCount)
+}
+
+func (r *BundleRegistry) FetchReleases(ctx context.Context, bundleType string, count int) ([]Build, error) {
+	url := fmt.Sprintf("%s/bundles/indexes/%s?count=%d", r.baseUrl, bundleType, count)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -92,19 +121,17 @@ func (r *BundleRegistry) FetchLatestRelease(ctx context.Context, bundleType stri
 		return nil, fmt.Errorf("error unmarshalling response body: %v", err)
 	}
 
-	if len(builds) == 0 {
-		return nil, errors.New("no builds found")
+	for i := range builds {
+		builds[i].BlobUrl = fmt.Sprintf("%s/bundles/blob/%s", r.baseUrl, builds[i].BlobUrl)
 	}
-
-	builds[0].BlobUrl = fmt.Sprintf("%s/bundles/blob/%s", r.baseUrl, builds[0].BlobUrl)
-	return &builds[0], nil
+	return builds, nil
 }
 
 func FilterBundleTypes(types []BundleType, platform Platform) []BundleType {
 	var filtered []BundleType
-	subStr := "-" + string(platform) + "-Win"
+	info := platform.Info()
 	for _, t := range types {
-		if strings.Contains(t.Name, subStr) {
+		if info.MatchesBundleType(t.Name) {
 			filtered = append(filtered, t)
 		}
 	}
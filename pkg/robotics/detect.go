@@ -0,0 +1,92 @@
+package robotics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PlatformRule maps serial numbers to a platform. A rule matches when the
+// serial starts with Prefix and, if Max is set, the number after the prefix
+// lies within [Min, Max].
+type PlatformRule struct {
+	Prefix   string   `mapstructure:"prefix"`
+	Min      uint64   `mapstructure:"min"`
+	Max      uint64   `mapstructure:"max"`
+	Platform Platform `mapstructure:"platform"`
+}
+
+// PlatformDetector infers the platform of a device from its serial number,
+// first from Rules and then, if LookupUrl is set, by asking the API.
+type PlatformDetector struct {
+	Rules     []PlatformRule
+	LookupUrl string
+}
+
+func (r *PlatformRule) Matches(serialNumber string) bool {
+	if r.Prefix == "" && r.Max == 0 {
+		return false
+	}
+	if !strings.HasPrefix(serialNumber, r.Prefix) {
+		return false
+	}
+	if r.Max == 0 {
+		return true
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(serialNumber, r.Prefix), 10, 64)
+	return err == nil && n >= r.Min && n <= r.Max
+}
+
+// Detect returns the detected platform or an empty platform when the serial
+// number is not covered by any rule or the API.
+func (d *PlatformDetector) Detect(ctx context.Context, serialNumber string) (Platform, error) {
+	for _, r := range d.Rules {
+		if r.Matches(serialNumber) {
+			return Platform(strings.ToUpper(string(r.Platform))), nil
+		}
+	}
+	if d.LookupUrl == "" {
+		return "", nil
+	}
+	return d.lookup(ctx, serialNumber)
+}
+
+func (d *PlatformDetector) lookup(ctx context.Context, serialNumber string) (Platform, error) {
+	url := fmt.Sprintf("%s/%s", d.LookupUrl, serialNumber)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	AddTifAuthHeaders(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode > 299 {
+		return "", fmt.Errorf("response failed with %s", resp.Status)
+	}
+
+	var device struct {
+		Platform string `json:"platform"`
+	}
+	if err = json.Unmarshal(body, &device); err != nil {
+		return "", fmt.Errorf("error unmarshalling response body: %v", err)
+	}
+	return Platform(strings.ToUpper(device.Platform)), nil
+}
@@ -0,0 +1,232 @@
+package robotics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GardenMap is the map.json of a Garden Simulator Packet. Coordinates are in
+// metres.
+type GardenMap struct {
+	Name            string           `json:"name"`
+	WorkAreas       []WorkArea       `json:"workAreas"`
+	BoundaryWires   []Wire           `json:"boundaryWires"`
+	GuideWires      []Wire           `json:"guideWires"`
+	ChargingStation *ChargingStation `json:"chargingStation"`
+}
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type WorkArea struct {
+	Id      string  `json:"id"`
+	Name    string  `json:"name"`
+	Polygon []Point `json:"polygon"`
+}
+
+type Wire struct {
+	Id     string  `json:"id"`
+	Points []Point `json:"points"`
+}
+
+type ChargingStation struct {
+	Position Point `json:"position"`
+	// Heading is in degrees, counter clockwise from the x axis.
+	Heading float64 `json:"heading"`
+}
+
+type MapValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *MapValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ParseGardenMap reads and validates a map.json. Validation problems are
+// returned together as *MapValidationError values joined into one error,
+// along with the map as far as it could be read.
+func ParseGardenMap(path string) (*GardenMap, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m GardenMap
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid map %s: %s", path, describeJSONError(b, err))
+	}
+	err = errors.Join(unknownFields(b), m.Validate())
+	if err != nil {
+		return &m, fmt.Errorf("invalid map %s:\n%w", path, err)
+	}
+	return &m, nil
+}
+
+// unknownFields reports the top level fields of data that GardenMap does not
+// have, which would otherwise be dropped without notice.
+func unknownFields(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	known := make(map[string]bool)
+	t := reflect.TypeOf(GardenMap{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		known[strings.ToLower(name)] = true
+	}
+
+	var names []string
+	for name := range fields {
+		if !known[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, &MapValidationError{Field: name, Message: "unknown field"})
+	}
+	return errors.Join(errs...)
+}
+
+func (m *GardenMap) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &MapValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(m.WorkAreas) == 0 {
+		invalid("workAreas", "at least one work area is required")
+	}
+	for i, a := range m.WorkAreas {
+		if len(a.Polygon) < 3 {
+			invalid(fmt.Sprintf("workAreas[%d].polygon", i), "needs at least 3 points, got %d", len(a.Polygon))
+		}
+	}
+
+	if len(m.BoundaryWires) == 0 {
+		invalid("boundaryWires", "at least one boundary wire is required")
+	}
+	for i, w := range m.BoundaryWires {
+		if len(w.Points) < 3 {
+			invalid(fmt.Sprintf("boundaryWires[%d].points", i), "a boundary wire must enclose an area, got %d points", len(w.Points))
+		}
+	}
+	for i, w := range m.GuideWires {
+		if len(w.Points) < 2 {
+			invalid(fmt.Sprintf("guideWires[%d].points", i), "needs at least 2 points, got %d", len(w.Points))
+		}
+	}
+
+	if m.ChargingStation == nil {
+		invalid("chargingStation", "is required")
+	}
+
+	for _, p := range m.points() {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			invalid("points", "coordinates must be finite numbers")
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Bounds returns the smallest rectangle containing every point of the map.
+func (m *GardenMap) Bounds() (min, max Point) {
+	points := m.points()
+	if len(points) == 0 {
+		return Point{}, Point{}
+	}
+	min, max = points[0], points[0]
+	for _, p := range points[1:] {
+		min.X = math.Min(min.X, p.X)
+		min.Y = math.Min(min.Y, p.Y)
+		max.X = math.Max(max.X, p.X)
+		max.Y = math.Max(max.Y, p.Y)
+	}
+	return min, max
+}
+
+// Area returns the enclosed area of the work area in square metres.
+func (a *WorkArea) Area() float64 {
+	var sum float64
+	for i, p := range a.Polygon {
+		q := a.Polygon[(i+1)%len(a.Polygon)]
+		sum += p.X*q.Y - q.X*p.Y
+	}
+	return math.Abs(sum) / 2
+}
+
+// Length returns the length of the wire in metres. When closed the segment
+// back to the first point is included, as for boundary wires.
+func (w *Wire) Length(closed bool) float64 {
+	var length float64
+	for i := 1; i < len(w.Points); i++ {
+		length += distance(w.Points[i-1], w.Points[i])
+	}
+	if closed && len(w.Points) > 2 {
+		length += distance(w.Points[len(w.Points)-1], w.Points[0])
+	}
+	return length
+}
+
+func (m *GardenMap) points() []Point {
+	var points []Point
+	for _, a := range m.WorkAreas {
+		points = append(points, a.Polygon...)
+	}
+	for _, w := range m.BoundaryWires {
+		points = append(points, w.Points...)
+	}
+	for _, w := range m.GuideWires {
+		points = append(points, w.Points...)
+	}
+	if m.ChargingStation != nil {
+		points = append(points, m.ChargingStation.Position)
+	}
+	return points
+}
+
+func distance(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// describeJSONError adds the line and column to syntax and type errors.
+func describeJSONError(data []byte, err error) string {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		if typeErr.Field != "" {
+			err = fmt.Errorf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+	default:
+		return err.Error()
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("line %d, column %d: %s", line, col, err)
+}
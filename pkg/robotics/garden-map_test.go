@@ -0,0 +1,97 @@
+package robotics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGardenMap(t *testing.T) {
+	m, err := ParseGardenMap(filepath.Join("testdata", "map.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.WorkAreas) != 1 || len(m.BoundaryWires) != 1 || len(m.GuideWires) != 1 {
+		t.Fatalf("got %d work areas, %d boundary wires and %d guide wires, want 1 of each",
+			len(m.WorkAreas), len(m.BoundaryWires), len(m.GuideWires))
+	}
+	if area := m.WorkAreas[0].Area(); area != 200 {
+		t.Errorf("work area is %.1f m², want 200", area)
+	}
+	if length := m.BoundaryWires[0].Length(true); length != 60 {
+		t.Errorf("boundary wire is %.1f m, want 60", length)
+	}
+	min, max := m.Bounds()
+	if min != (Point{0, 0}) || max != (Point{20, 10}) {
+		t.Errorf("bounds are %v %v, want {0 0} {20 10}", min, max)
+	}
+}
+
+func TestParseGardenMapInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		fields []string
+	}{
+		{
+			name:   "empty",
+			json:   `{}`,
+			fields: []string{"workAreas", "boundaryWires", "chargingStation"},
+		},
+		{
+			name:   "unknown field",
+			json:   `{"workAreas": [{"polygon": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 0, "y": 1}]}], "boundaryWires": [{"points": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 0, "y": 1}]}], "chargingStation": {}, "obstacles": []}`,
+			fields: []string{"obstacles"},
+		},
+		{
+			name:   "short polygon",
+			json:   `{"workAreas": [{"polygon": [{"x": 0, "y": 0}]}], "boundaryWires": [{"points": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 0, "y": 1}]}], "chargingStation": {}}`,
+			fields: []string{"workAreas[0].polygon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "map.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := ParseGardenMap(path)
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			if m == nil {
+				t.Error("expected the map to be returned with the validation error")
+			}
+
+			fields := invalidFields(err)
+			if len(fields) != len(tt.fields) {
+				t.Fatalf("got invalid fields %v, want %v", fields, tt.fields)
+			}
+			for i := range fields {
+				if fields[i] != tt.fields[i] {
+					t.Errorf("got invalid fields %v, want %v", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
+
+// invalidFields returns the fields of every MapValidationError in err.
+func invalidFields(err error) []string {
+	switch err := err.(type) {
+	case *MapValidationError:
+		return []string{err.Field}
+	case interface{ Unwrap() []error }:
+		var fields []string
+		for _, e := range err.Unwrap() {
+			fields = append(fields, invalidFields(e)...)
+		}
+		return fields
+	case interface{ Unwrap() error }:
+		return invalidFields(err.Unwrap())
+	}
+	return nil
+}
@@ -3,23 +3,47 @@ package robotics
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
 	"github.com/Tifufu/gsim-web-launch/pkg/ext"
 	"github.com/charmbracelet/log"
 )
 
+// gspLayoutFile marks a GSP cache directory as using the
+// <serial>/<platform> layout.
+const gspLayoutFile = ".layout"
+
+// unknownGSPPlatform holds a GSP cached by an earlier version that did not
+// record its platform. It is never used for a launch, as it may belong to
+// another platform, but can be claimed with gsp import.
+const unknownGSPPlatform = "unknown"
+
 type GSPRegistry struct {
-	cacheDir string
-	baseUrl  string
+	// MaxSize and MaxEntries bound the cache. Least recently used entries
+	// are evicted once either is exceeded. Zero means unlimited.
+	MaxSize    int64
+	MaxEntries int
+	// TTL is how long a cached GSP is used before it is re-validated
+	// against the server. Zero means cached GSPs never expire.
+	TTL          time.Duration
+	ForceRefresh bool
+	cacheDir     string
+	baseUrl      string
 }
 
 type GSPPaths struct {
+	Dir        string
 	Map        string
 	TestBundle string
+	// Stale is set when the GSP has expired but could not be refreshed.
+	Stale bool
 }
 
 func NewGSPRegistry(cacheDir, baseUrl string) *GSPRegistry {
@@ -30,38 +54,305 @@ func NewGSPRegistry(cacheDir, baseUrl string) *GSPRegistry {
 }
 
 func (r *GSPRegistry) GetGSP(serialNumber, platform string) (*GSPPaths, error) {
-	gsp, err := r.GetGSPFromCache(serialNumber)
+	gsp, err := r.getGSP(serialNumber, platform)
 	if err != nil {
 		return nil, err
 	}
-	if gsp != nil {
+	if err := cache.Touch(r.entryDir(serialNumber, platform)); err != nil {
+		log.Warn("Failed to record GSP usage", "err", err)
+	}
+	if err := r.Evict(gspKey(serialNumber, platform)); err != nil {
+		log.Warn("Failed to evict GSPs", "err", err)
+	}
+	return gsp, nil
+}
+
+// Evict removes least recently used GSPs until the cache is within its
+// limits. The entry with the <serial>/<platform> key keep and entries in use
+// by a running session are never evicted.
+func (r *GSPRegistry) Evict(keep string) error {
+	if r.MaxSize <= 0 && r.MaxEntries <= 0 {
+		return nil
+	}
+
+	entries, err := cache.ListEntries(cache.KindGSP, r.cacheDir, 2)
+	if err != nil {
+		return err
+	}
+	cache.SortByLastUsed(entries)
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	count := len(entries)
+
+	for _, e := range entries {
+		overSize := r.MaxSize > 0 && size > r.MaxSize
+		overCount := r.MaxEntries > 0 && count > r.MaxEntries
+		if !overSize && !overCount {
+			break
+		}
+		if e.Key == keep || cache.InUse(e.Dir) {
+			continue
+		}
+
+		if err := cache.RemoveEntry(e); err != nil {
+			return fmt.Errorf("failed to evict GSP %s: %w", e.Key, err)
+		}
+		log.Debug("Evicted GSP", "key", e.Key, "size", cache.FormatSize(e.Size))
+		size -= e.Size
+		count--
+	}
+	return nil
+}
+
+func (r *GSPRegistry) getGSP(serialNumber, platform string) (*GSPPaths, error) {
+	dir := r.entryDir(serialNumber, platform)
+	gsp, err := r.GetGSPFromCache(serialNumber, platform)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := cache.ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if gsp != nil && !r.ForceRefresh && !r.expired(manifest) {
 		log.Debug("Using cached GSP")
 		return gsp, nil
 	}
 
+	if gsp != nil && cache.InUse(dir) {
+		// Replacing it would pull the map from under the running session.
+		log.Warn("GSP is in use by a running session, using the cached GSP", "serial", serialNumber, "platform", platform)
+		gsp.Stale = true
+		return gsp, nil
+	}
+
+	if r.ForceRefresh {
+		manifest = nil
+	}
+	fresh, err := r.fetch(dir, serialNumber, platform, manifest)
+	if err != nil {
+		if gsp != nil {
+			log.Warn("Failed to refresh GSP, using stale cached GSP", "err", err)
+			gsp.Stale = true
+			return gsp, nil
+		}
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// fetch downloads the GSP into dir. When cached holds validators the request
+// is conditional and a 304 response keeps the cached GSP.
+func (r *GSPRegistry) fetch(dir, serialNumber, platform string, cached *cache.Manifest) (*GSPPaths, error) {
 	endpoint := fmt.Sprintf("%s/packet/%s/%s", r.baseUrl, serialNumber, platform)
 	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	AddTifAuthHeaders(req)
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached != nil && cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(r.cacheDir, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
 
-	dir := filepath.Join(r.cacheDir, serialNumber)
-	err = ext.DownloadAndUnpack(req, dir)
+	header, err := ext.DownloadAndUnpackWithHeaders(req, tmpDir)
+	if errors.Is(err, ext.ErrNotModified) && cached != nil {
+		log.Debug("Cached GSP is up to date")
+		cached.CheckedAt = time.Now()
+		if err := cache.WriteManifest(dir, cached); err != nil {
+			log.Warn("Failed to update GSP cache manifest", "err", err)
+		}
+		return LocateGSPPaths(dir, serialNumber)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	gsp, err = LocateGSPPaths(dir, serialNumber)
+	downloaded, err := LocateGSPPaths(tmpDir, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseGardenMap(downloaded.Map); err != nil {
+		log.Warn("GSP map did not pass validation, using it anyway", "serial", serialNumber, "err", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove outdated GSP: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return nil, err
+	}
+
+	gsp, err := LocateGSPPaths(dir, serialNumber)
 	if err != nil {
 		return nil, err
 	}
 
+	checksums, err := cache.HashDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash GSP: %w", err)
+	}
+	now := time.Now()
+	err = cache.WriteManifest(dir, &cache.Manifest{
+		Kind:         cache.KindGSP,
+		Key:          serialNumber,
+		Platform:     platform,
+		DownloadedAt: now,
+		CheckedAt:    now,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Checksums:    checksums,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write GSP cache manifest: %w", err)
+	}
+
 	return gsp, nil
 }
 
-func (r *GSPRegistry) GetGSPFromCache(serialNumber string) (*GSPPaths, error) {
-	dir := filepath.Join(r.cacheDir, serialNumber)
+// expired reports whether a cached GSP should be re-validated. Imported GSPs
+// may not exist on the server so they only expire when a refresh is forced.
+func (r *GSPRegistry) expired(m *cache.Manifest) bool {
+	if r.TTL <= 0 || (m != nil && m.Imported) {
+		return false
+	}
+	if m == nil {
+		return true
+	}
+	return time.Since(m.CheckedAt) > r.TTL
+}
+
+// ImportGSP registers a GSP from a local zip file or directory in the cache
+// under serialNumber and platform. An empty serialNumber is detected from the
+// test bundle in the packet.
+func (r *GSPRegistry) ImportGSP(src, serialNumber, platform string) (*GSPPaths, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(r.cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(r.cacheDir, ".import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if info.IsDir() {
+		err = ext.CopyDir(src, tmpDir)
+	} else {
+		err = ext.Unzip(src, tmpDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GSP %s: %w", src, err)
+	}
+
+	if serialNumber == "" {
+		serialNumber, err = DetectGSPSerial(tmpDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	imported, err := LocateGSPPaths(tmpDir, serialNumber)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a GSP for %s: %w", src, serialNumber, err)
+	}
+	if _, err := ParseGardenMap(imported.Map); err != nil {
+		log.Warn("GSP map did not pass validation, using it anyway", "serial", serialNumber, "err", err)
+	}
+
+	dir := r.entryDir(serialNumber, platform)
+	if cache.InUse(dir) {
+		return nil, fmt.Errorf("the cached GSP for %s is in use by a running session", gspKey(serialNumber, platform))
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to replace cached GSP: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return nil, err
+	}
+
+	checksums, err := cache.HashDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash GSP: %w", err)
+	}
+	now := time.Now()
+	err = cache.WriteManifest(dir, &cache.Manifest{
+		Kind:         cache.KindGSP,
+		Key:          serialNumber,
+		Platform:     platform,
+		DownloadedAt: now,
+		CheckedAt:    now,
+		LastUsed:     now,
+		Imported:     true,
+		Checksums:    checksums,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write GSP cache manifest: %w", err)
+	}
+	log.Debug("Imported GSP", "serial", serialNumber, "platform", platform, "src", src)
+
+	if unknown := r.entryDir(serialNumber, unknownGSPPlatform); platform != unknownGSPPlatform && sameDir(src, unknown) {
+		// The GSP migrated without a platform was claimed for platform.
+		if err := os.RemoveAll(unknown); err != nil {
+			log.Warn("Failed to remove claimed GSP", "dir", unknown, "err", err)
+		}
+	}
+
+	if err := r.Evict(gspKey(serialNumber, platform)); err != nil {
+		log.Warn("Failed to evict GSPs", "err", err)
+	}
+	return LocateGSPPaths(dir, serialNumber)
+}
+
+var testBundleSerialRegexp = regexp.MustCompile(`(\d+)\.zip$`)
+
+// DetectGSPSerial finds the serial number from the name of the test bundle,
+// which ends with the serial number, in an unpacked GSP.
+func DetectGSPSerial(dir string) (string, error) {
+	serials := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if match := testBundleSerialRegexp.FindStringSubmatch(d.Name()); !d.IsDir() && match != nil {
+			serials[match[1]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(serials) != 1 {
+		return "", fmt.Errorf("could not detect the serial number of the GSP in %s, found %d candidates", dir, len(serials))
+	}
+	for s := range serials {
+		return s, nil
+	}
+	return "", nil
+}
+
+func (r *GSPRegistry) GetGSPFromCache(serialNumber, platform string) (*GSPPaths, error) {
+	dir := r.entryDir(serialNumber, platform)
 	_, err := os.Stat(dir)
 	if err == nil {
 		return LocateGSPPaths(dir, serialNumber)
@@ -72,8 +363,108 @@ func (r *GSPRegistry) GetGSPFromCache(serialNumber string) (*GSPPaths, error) {
 	return nil, err
 }
 
+// MigrateLayout moves GSPs cached under <serial> by earlier versions to
+// <serial>/<platform>. It runs once per cache directory. Entries whose
+// platform was never recorded are moved to <serial>/unknown.
+func (r *GSPRegistry) MigrateLayout() error {
+	marker := filepath.Join(r.cacheDir, gspLayoutFile)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(r.cacheDir, 0755); err != nil {
+		return err
+	}
+
+	dirents, err := os.ReadDir(r.cacheDir)
+	if err != nil {
+		return err
+	}
+	for _, d := range dirents {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		serialNumber := d.Name()
+		dir := filepath.Join(r.cacheDir, serialNumber)
+
+		manifest, err := cache.ReadManifest(dir)
+		if err != nil {
+			return err
+		}
+		platform := unknownGSPPlatform
+		if manifest != nil && manifest.Platform != "" {
+			platform = manifest.Platform
+		}
+
+		tmpDir := filepath.Join(r.cacheDir, ".migrate-"+serialNumber)
+		if err := os.Rename(dir, tmpDir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpDir, r.entryDir(serialNumber, platform)); err != nil {
+			return err
+		}
+		log.Debug("Migrated cached GSP", "serial", serialNumber, "platform", platform)
+	}
+
+	return os.WriteFile(marker, []byte(gspKey("<serial>", "<platform>")), 0644)
+}
+
+// CachedSerials returns the serial numbers that have cached GSPs.
+func (r *GSPRegistry) CachedSerials() ([]string, error) {
+	dirents, err := os.ReadDir(r.cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var serials []string
+	for _, d := range dirents {
+		if d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			serials = append(serials, d.Name())
+		}
+	}
+	return serials, nil
+}
+
+// CachedPlatforms returns the platforms a serial number has cached GSPs for.
+func (r *GSPRegistry) CachedPlatforms(serialNumber string) ([]string, error) {
+	dirents, err := os.ReadDir(filepath.Join(r.cacheDir, serialNumber))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var platforms []string
+	for _, d := range dirents {
+		if d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			platforms = append(platforms, d.Name())
+		}
+	}
+	return platforms, nil
+}
+
+func sameDir(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && strings.EqualFold(a, b)
+}
+
+func (r *GSPRegistry) entryDir(serialNumber, platform string) string {
+	return filepath.Join(r.cacheDir, serialNumber, platform)
+}
+
+func gspKey(serialNumber, platform string) string {
+	return serialNumber + "/" + platform
+}
+
 func LocateGSPPaths(dir string, serialNumber string) (*GSPPaths, error) {
-	gspPaths := &GSPPaths{}
+	gspPaths := &GSPPaths{Dir: dir}
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		switch {
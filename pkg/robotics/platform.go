@@ -1,66 +1,227 @@
 package robotics
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"regexp"
 	"strings"
 )
 
 type Platform string
 
-var (
-	platforms []Platform
-)
+const defaultWinMowerPort = 4250
 
-const (
-	P25   Platform = "P25"
-	P2    Platform = "P2"
-	P16   Platform = "P16"
-	P01G  Platform = "P01G"
-	P2Z   Platform = "P2Z"
-	P3    Platform = "P3"
-	P005  Platform = "P005"
-	P21   Platform = "P21"
-	P14_2 Platform = "P14_2"
-	P14_1 Platform = "P14_1"
-	P005H Platform = "P005H"
-	P17   Platform = "P17"
-	P22   Platform = "P22"
-)
+var catalog = DefaultPlatformCatalog()
 
-func (e *Platform) String() string {
-	return string(*e)
+// PlatformInfo describes a mower platform. Platforms are loaded from config
+// and optionally a remote catalog so new models do not need a release.
+type PlatformInfo struct {
+	Name        Platform `json:"name" mapstructure:"name"`
+	DisplayName string   `json:"displayName" mapstructure:"displayName"`
+	// BundleTypePattern is a regular expression matched against bundle type
+	// names to find the WinMower builds of the platform.
+	BundleTypePattern string `json:"bundleTypePattern" mapstructure:"bundleTypePattern"`
+	WinMowerPort      int    `json:"winMowerPort" mapstructure:"winMowerPort"`
+	// WinMowerAddress overrides WinMowerPort with a full host:port address,
+	// or "auto" to pick a free port for every launch.
+	WinMowerAddress string `json:"winMowerAddress" mapstructure:"winMowerAddress"`
+	// SimulatorOptions are simulator arguments, without the leading dash,
+	// used by default for the platform.
+	SimulatorOptions map[string]any `json:"simulatorOptions" mapstructure:"simulatorOptions"`
+
+	bundleTypeRegexp *regexp.Regexp
 }
 
-func GetPlatforms() []Platform {
-	if platforms == nil {
-		platforms = []Platform{
-			P25,
-			P2,
-			P16,
-			P01G,
-			P2Z,
-			P3,
-			P005,
-			P21,
-			P14_2,
-			P14_1,
-			P005H,
-			P17,
-			P22,
+type PlatformCatalog struct {
+	platforms []PlatformInfo
+}
+
+// DefaultPlatformInfos returns the platforms known when no catalog is configured.
+func DefaultPlatformInfos() []PlatformInfo {
+	names := []string{"P25", "P2", "P16", "P01G", "P2Z", "P3", "P005", "P21", "P14_2", "P14_1", "P005H", "P17", "P22"}
+	infos := make([]PlatformInfo, 0, len(names))
+	for _, n := range names {
+		infos = append(infos, PlatformInfo{
+			Name:              Platform(n),
+			DisplayName:       n,
+			BundleTypePattern: regexp.QuoteMeta("-" + n + "-Win"),
+			WinMowerPort:      defaultWinMowerPort,
+		})
+	}
+	return infos
+}
+
+func DefaultPlatformCatalog() *PlatformCatalog {
+	c, err := NewPlatformCatalog(DefaultPlatformInfos())
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewPlatformCatalog validates infos and fills in defaults. Later entries
+// replace earlier ones with the same name, so remote catalogs can be
+// appended to the configured one.
+func NewPlatformCatalog(infos []PlatformInfo) (*PlatformCatalog, error) {
+	c := &PlatformCatalog{}
+	for _, info := range infos {
+		info.Name = Platform(strings.ToUpper(string(info.Name)))
+		if info.Name == "" {
+			return nil, fmt.Errorf("platform without a name in catalog")
 		}
+		if info.DisplayName == "" {
+			info.DisplayName = string(info.Name)
+		}
+		if info.BundleTypePattern == "" {
+			info.BundleTypePattern = regexp.QuoteMeta("-" + string(info.Name) + "-Win")
+		}
+		if info.WinMowerPort == 0 {
+			info.WinMowerPort = defaultWinMowerPort
+		}
+
+		re, err := regexp.Compile(info.BundleTypePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle type pattern for platform %s: %w", info.Name, err)
+		}
+		info.bundleTypeRegexp = re
+
+		if i := c.index(info.Name); i >= 0 {
+			c.platforms[i] = info
+		} else {
+			c.platforms = append(c.platforms, info)
+		}
+	}
+
+	if len(c.platforms) == 0 {
+		return nil, fmt.Errorf("platform catalog is empty")
+	}
+	return c, nil
+}
+
+// FetchPlatformCatalog reads a JSON array of platforms from url.
+func FetchPlatformCatalog(ctx context.Context, url string) ([]PlatformInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	AddTifAuthHeaders(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if resp.StatusCode > 299 {
+		return nil, fmt.Errorf("response failed with %s", resp.Status)
+	}
+
+	var infos []PlatformInfo
+	if err = json.Unmarshal(body, &infos); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response body: %v", err)
+	}
+	return infos, nil
+}
+
+func SetPlatformCatalog(c *PlatformCatalog) {
+	catalog = c
+}
+
+func GetPlatformCatalog() *PlatformCatalog {
+	return catalog
+}
+
+func (c *PlatformCatalog) Lookup(p Platform) (*PlatformInfo, bool) {
+	i := c.index(Platform(strings.ToUpper(string(p))))
+	if i < 0 {
+		return nil, false
+	}
+	return &c.platforms[i], true
+}
+
+func (c *PlatformCatalog) Platforms() []Platform {
+	platforms := make([]Platform, 0, len(c.platforms))
+	for _, info := range c.platforms {
+		platforms = append(platforms, info.Name)
 	}
 	return platforms
 }
 
+func (c *PlatformCatalog) index(p Platform) int {
+	for i, info := range c.platforms {
+		if info.Name == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Info returns the catalog entry of the platform, falling back to the
+// defaults for platforms that are not in the catalog.
+func (e Platform) Info() *PlatformInfo {
+	if info, ok := catalog.Lookup(e); ok {
+		return info
+	}
+	return &PlatformInfo{
+		Name:             e,
+		DisplayName:      string(e),
+		WinMowerPort:     defaultWinMowerPort,
+		bundleTypeRegexp: regexp.MustCompile(regexp.QuoteMeta("-" + string(e) + "-Win")),
+	}
+}
+
+// DefaultSimulatorOptions returns SimulatorOptions with the values formatted
+// as they are passed on the command line.
+func (i *PlatformInfo) DefaultSimulatorOptions() map[string]string {
+	options := make(map[string]string, len(i.SimulatorOptions))
+	for name, value := range i.SimulatorOptions {
+		options[name] = fmt.Sprint(value)
+	}
+	return options
+}
+
+// DefaultWinMowerAddress returns the address WinMower listens on for the
+// platform unless a session overrides it.
+func (i *PlatformInfo) DefaultWinMowerAddress() string {
+	if i.WinMowerAddress != "" {
+		return i.WinMowerAddress
+	}
+	return i.BuiltinWinMowerAddress()
+}
+
+// BuiltinWinMowerAddress returns the address the WinMower of the platform
+// listens on, and the simulator connects to, when they are given none.
+func (i *PlatformInfo) BuiltinWinMowerAddress() string {
+	return fmt.Sprintf("127.0.0.1:%d", i.WinMowerPort)
+}
+
+func (i *PlatformInfo) MatchesBundleType(name string) bool {
+	return i.bundleTypeRegexp.MatchString(name)
+}
+
+func (e *Platform) String() string {
+	return string(*e)
+}
+
+func GetPlatforms() []Platform {
+	return catalog.Platforms()
+}
+
 func (e *Platform) Set(s string) error {
 	s = strings.ToUpper(s)
-	switch s {
-	case "P25", "P2", "P16", "P01G", "P2Z", "P3", "P005", "P21", "P14_2", "P14_1", "P005H", "P17", "P22":
-		*e = Platform(s)
-		return nil
-	default:
+	if _, ok := catalog.Lookup(Platform(s)); !ok {
 		return fmt.Errorf("invalid platform: %s. Must be one of %v", s, GetPlatforms())
 	}
+	*e = Platform(s)
+	return nil
 }
 
 func (e *Platform) Type() string {
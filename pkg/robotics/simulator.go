@@ -3,64 +3,287 @@ package robotics
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"net/http"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
 	"github.com/Tifufu/gsim-web-launch/pkg/ext"
 	"github.com/charmbracelet/log"
 )
 
+const (
+	simulatorBundleType = "GardenSimulator"
+	activeSimulatorFile = "active"
+	// unknownSimulatorVersion holds a simulator installed before builds were
+	// kept in versioned directories.
+	unknownSimulatorVersion = "unknown"
+)
+
+// SimulatorRegistry keeps each simulator build in its own subdirectory of
+// cacheDir, named after the build id, and records which one is active.
 type SimulatorRegistry struct {
+	UpdatePolicy   UpdatePolicy
 	cacheDir       string
 	bundleRegistry *BundleRegistry
+	migrateOnce    sync.Once
 }
 
 type Simulator struct {
-	Path string
+	Path         string
+	BuildId      string
+	DownloadedAt time.Time
+	manifest     *cache.Manifest
 }
 
 func NewSimulatorRegistry(cacheDir string, bregsitry *BundleRegistry) *SimulatorRegistry {
 	return &SimulatorRegistry{
 		bundleRegistry: bregsitry,
 		cacheDir:       cacheDir,
+		UpdatePolicy:   UpdatePolicy{Mode: UpdateNever},
 	}
 }
 
+// GetSimulator returns the active simulator, downloading and activating the
+// latest release when the update policy asks for a check.
 func (s *SimulatorRegistry) GetSimulator(ctx context.Context) (*Simulator, error) {
+	sim, err := s.getSimulator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.markUsed(sim)
+	return sim, nil
+}
+
+func (s *SimulatorRegistry) getSimulator(ctx context.Context) (*Simulator, error) {
 	sim, err := s.GetCachedSimulator(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if sim != nil {
-		log.Debug("Using cached simulator")
+	if sim != nil && !s.UpdatePolicy.ShouldCheck(sim.lastChecked()) {
+		log.Debug("Using cached simulator", "build", sim.BuildId)
 		return sim, nil
 	}
 
 	log.Debug("Fetching simulator...")
-	latestBuild, err := s.bundleRegistry.FetchLatestRelease(ctx, "GardenSimulator")
+	latestBuild, err := s.bundleRegistry.FetchLatestRelease(ctx, simulatorBundleType)
 	if err != nil {
+		if sim != nil {
+			log.Warn("Failed to check for simulator update, using cached simulator", "err", err)
+			return sim, nil
+		}
 		return nil, err
 	}
 	log.Debugf("Latest Simulator build: %s\n", latestBuild.BlobUrl)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", latestBuild.BlobUrl, nil)
+	if sim != nil && sim.BuildId == latestBuild.Id {
+		log.Debug("Cached simulator is up to date", "build", sim.BuildId)
+		sim.manifest.CheckedAt = time.Now()
+		if err := cache.WriteManifest(s.versionDir(sim.BuildId), sim.manifest); err != nil {
+			log.Warn("Failed to update simulator cache manifest", "err", err)
+		}
+		return sim, nil
+	}
+
+	latest, err := s.getCachedVersion(latestBuild.Id)
 	if err != nil {
 		return nil, err
 	}
-	AddTifAuthHeaders(req)
-	log.Debug("Downloading and unpacking simulator...")
-	err = ext.DownloadAndUnpack(req, s.cacheDir)
+	if latest == nil {
+		latest, err = s.download(ctx, latestBuild)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sim != nil {
+		log.Info("Updating simulator", "from", sim.BuildId, "to", latest.BuildId)
+	}
+	if err := s.UseSimulator(latest.BuildId); err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// GetSimulatorVersion returns a specific simulator build, downloading it if
+// needed. It does not change the active version.
+func (s *SimulatorRegistry) GetSimulatorVersion(ctx context.Context, buildId string) (*Simulator, error) {
+	sim, err := s.getCachedVersion(buildId)
 	if err != nil {
 		return nil, err
 	}
+	if sim != nil {
+		log.Debug("Using cached simulator", "build", buildId)
+		s.markUsed(sim)
+		return sim, nil
+	}
 
-	return s.GetCachedSimulator(ctx)
+	build, err := s.bundleRegistry.FetchRelease(ctx, simulatorBundleType, buildId)
+	if err != nil {
+		return nil, err
+	}
+	sim, err = s.download(ctx, build)
+	if err != nil {
+		return nil, err
+	}
+	s.markUsed(sim)
+	return sim, nil
 }
 
+// GetCachedSimulator returns the active simulator or nil if none is cached.
 func (s *SimulatorRegistry) GetCachedSimulator(ctx context.Context) (*Simulator, error) {
+	active, err := s.ActiveVersion()
+	if err != nil {
+		return nil, err
+	}
+	if active == "" {
+		return nil, nil
+	}
+	return s.getCachedVersion(active)
+}
+
+// ActiveVersion returns the build id of the active simulator. When none has
+// been selected the most recently downloaded version is used.
+func (s *SimulatorRegistry) ActiveVersion() (string, error) {
+	s.migrateOnce.Do(s.migrateFlatLayout)
+
+	b, err := os.ReadFile(filepath.Join(s.cacheDir, activeSimulatorFile))
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	sims, err := s.ListSimulators()
+	if err != nil || len(sims) == 0 {
+		return "", err
+	}
+	return sims[len(sims)-1].BuildId, nil
+}
+
+// ListSimulators returns the cached simulator versions, oldest first.
+func (s *SimulatorRegistry) ListSimulators() ([]*Simulator, error) {
+	s.migrateOnce.Do(s.migrateFlatLayout)
+
+	entries, err := os.ReadDir(s.cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sims []*Simulator
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		sim, err := s.getCachedVersion(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if sim != nil {
+			sims = append(sims, sim)
+		}
+	}
+
+	sort.Slice(sims, func(i, j int) bool {
+		return sims[i].DownloadedAt.Before(sims[j].DownloadedAt)
+	})
+	return sims, nil
+}
+
+func (s *SimulatorRegistry) UseSimulator(buildId string) error {
+	sim, err := s.getCachedVersion(buildId)
+	if err != nil {
+		return err
+	}
+	if sim == nil {
+		return fmt.Errorf("simulator version %s is not cached", buildId)
+	}
+	return os.WriteFile(filepath.Join(s.cacheDir, activeSimulatorFile), []byte(buildId), 0644)
+}
+
+func (s *SimulatorRegistry) RemoveSimulator(buildId string) error {
+	sim, err := s.getCachedVersion(buildId)
+	if err != nil {
+		return err
+	}
+	if sim == nil {
+		return fmt.Errorf("simulator version %s is not cached", buildId)
+	}
+
+	active, err := s.ActiveVersion()
+	if err != nil {
+		return err
+	}
+	if active == buildId {
+		err := os.Remove(filepath.Join(s.cacheDir, activeSimulatorFile))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.RemoveAll(s.versionDir(buildId))
+}
+
+func (s *SimulatorRegistry) download(ctx context.Context, build *Build) (*Simulator, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", build.BlobUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	AddTifAuthHeaders(req)
+
+	dir := s.versionDir(build.Id)
+	log.Debug("Downloading and unpacking simulator...", "build", build.Id)
+	err = ext.DownloadAndUnpack(req, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := cache.HashDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash simulator: %w", err)
+	}
+	now := time.Now()
+	err = cache.WriteManifest(dir, &cache.Manifest{
+		Kind:         cache.KindSimulator,
+		Key:          build.Id,
+		BuildId:      build.Id,
+		BundleType:   simulatorBundleType,
+		DownloadedAt: now,
+		CheckedAt:    now,
+		Checksums:    checksums,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write simulator cache manifest: %w", err)
+	}
+
+	sim, err := s.getCachedVersion(build.Id)
+	if err != nil {
+		return nil, err
+	}
+	if sim == nil {
+		return nil, fmt.Errorf("no GardenSimulator.exe found in simulator build %s", build.Id)
+	}
+	return sim, nil
+}
+
+func (s *SimulatorRegistry) getCachedVersion(buildId string) (*Simulator, error) {
+	dir := s.versionDir(buildId)
+	manifest, err := cache.ReadManifest(dir)
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+
 	var exePath string
-	err := filepath.Walk(s.cacheDir, func(path string, info fs.FileInfo, err error) error {
+	err = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
 		if filepath.Base(path) == "GardenSimulator.exe" {
 			exePath = path
 			return nil
@@ -74,7 +297,144 @@ func (s *SimulatorRegistry) GetCachedSimulator(ctx context.Context) (*Simulator,
 	if err != nil {
 		return nil, err
 	}
+
 	return &Simulator{
-		Path: exePath,
+		Path:         exePath,
+		BuildId:      manifest.BuildId,
+		DownloadedAt: manifest.DownloadedAt,
+		manifest:     manifest,
 	}, nil
 }
+
+func (s *SimulatorRegistry) markUsed(sim *Simulator) {
+	if err := cache.Touch(s.versionDir(sim.BuildId)); err != nil {
+		log.Warn("Failed to record simulator usage", "err", err)
+	}
+}
+
+func (s *SimulatorRegistry) versionDir(buildId string) string {
+	return filepath.Join(s.cacheDir, buildId)
+}
+
+// migrateFlatLayout moves a simulator that was unpacked directly into
+// cacheDir into its own version directory and makes it the active one.
+// Installs from before the cache had manifests have no build id and are moved
+// into the version directory "unknown".
+func (s *SimulatorRegistry) migrateFlatLayout() {
+	manifest, err := cache.ReadManifest(s.cacheDir)
+	if err != nil {
+		log.Warn("Failed to migrate cached simulator", "err", err)
+		return
+	}
+	buildId := unknownSimulatorVersion
+	if manifest != nil && manifest.BuildId != "" {
+		buildId = manifest.BuildId
+	}
+
+	entries, err := s.flatLayoutEntries()
+	if err != nil {
+		log.Warn("Failed to migrate cached simulator", "err", err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if manifest == nil && !containsSimulatorExe(s.cacheDir, entries) {
+		return
+	}
+
+	dir := s.versionDir(buildId)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warn("Failed to migrate cached simulator", "err", err)
+		return
+	}
+	for _, name := range entries {
+		if name == buildId {
+			continue
+		}
+		if err := os.Rename(filepath.Join(s.cacheDir, name), filepath.Join(dir, name)); err != nil {
+			log.Warn("Failed to migrate cached simulator", "err", err)
+			return
+		}
+	}
+
+	if manifest == nil {
+		checksums, err := cache.HashDir(dir)
+		if err != nil {
+			log.Warn("Failed to hash migrated simulator", "err", err)
+		}
+		// CheckedAt is left unset so the next launch checks for a release
+		// with a known build id.
+		err = cache.WriteManifest(dir, &cache.Manifest{
+			Kind:         cache.KindSimulator,
+			Key:          buildId,
+			BuildId:      buildId,
+			BundleType:   simulatorBundleType,
+			DownloadedAt: time.Now(),
+			Checksums:    checksums,
+		})
+		if err != nil {
+			log.Warn("Failed to write migrated simulator cache manifest", "err", err)
+			return
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(s.cacheDir, activeSimulatorFile)); os.IsNotExist(err) {
+		err = os.WriteFile(filepath.Join(s.cacheDir, activeSimulatorFile), []byte(buildId), 0644)
+		if err != nil {
+			log.Warn("Failed to mark migrated simulator as active", "err", err)
+		}
+	}
+	log.Debug("Migrated cached simulator to versioned layout", "build", buildId)
+}
+
+// flatLayoutEntries returns the entries of cacheDir that are not version
+// directories or the active version file.
+func (s *SimulatorRegistry) flatLayoutEntries() ([]string, error) {
+	entries, err := os.ReadDir(s.cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Name() == activeSimulatorFile {
+			continue
+		}
+		if e.IsDir() {
+			m, err := cache.ReadManifest(filepath.Join(s.cacheDir, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				continue
+			}
+		}
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+func containsSimulatorExe(dir string, names []string) bool {
+	found := false
+	for _, name := range names {
+		filepath.Walk(filepath.Join(dir, name), func(path string, info fs.FileInfo, err error) error {
+			if err == nil && filepath.Base(path) == "GardenSimulator.exe" {
+				found = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	}
+	return found
+}
+
+func (sim *Simulator) lastChecked() time.Time {
+	if sim.manifest == nil {
+		return time.Time{}
+	}
+	return sim.manifest.CheckedAt
+}
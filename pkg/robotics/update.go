@@ -0,0 +1,45 @@
+package robotics
+
+import (
+	"fmt"
+	"time"
+)
+
+type UpdateMode string
+
+const (
+	UpdateAlways   UpdateMode = "always"
+	UpdateInterval UpdateMode = "interval"
+	UpdateNever    UpdateMode = "never"
+)
+
+// UpdatePolicy decides when a cached build is compared against the latest release.
+type UpdatePolicy struct {
+	Mode     UpdateMode
+	Interval time.Duration
+}
+
+func ParseUpdatePolicy(mode string, intervalHours int) (UpdatePolicy, error) {
+	switch m := UpdateMode(mode); m {
+	case UpdateAlways, UpdateNever:
+		return UpdatePolicy{Mode: m}, nil
+	case UpdateInterval:
+		if intervalHours <= 0 {
+			return UpdatePolicy{}, fmt.Errorf("update interval must be positive, got %d hours", intervalHours)
+		}
+		return UpdatePolicy{Mode: m, Interval: time.Duration(intervalHours) * time.Hour}, nil
+	default:
+		return UpdatePolicy{}, fmt.Errorf("invalid update policy: %s. Must be one of [%s %s %s]", mode, UpdateAlways, UpdateInterval, UpdateNever)
+	}
+}
+
+func (p UpdatePolicy) ShouldCheck(lastChecked time.Time) bool {
+	switch p.Mode {
+	case UpdateAlways:
+		return true
+	case UpdateInterval:
+		return time.Since(lastChecked) >= p.Interval
+	default:
+		return false
+	}
+}
@@ -5,67 +5,131 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
 	"github.com/Tifufu/gsim-web-launch/pkg/ext"
 	"github.com/charmbracelet/log"
 )
 
 type WinMowerRegistry struct {
-	CacheDir       string
+	CacheDir     string
+	UpdatePolicy UpdatePolicy
+	// BundleType, when set, is used instead of the newest bundle type of
+	// the platform. It must be a bundle type of the platform and is cached
+	// apart from the platform's default one.
+	BundleType     string
 	bundleRegistry *BundleRegistry
 }
 
 type WinMower struct {
-	Path string
+	Path       string
+	BuildId    string
+	BundleType string
+	manifest   *cache.Manifest
 }
 
 func NewWinMowerRegistry(cacheDir string, bregsitry *BundleRegistry) *WinMowerRegistry {
 	return &WinMowerRegistry{
 		bundleRegistry: bregsitry,
 		CacheDir:       cacheDir,
+		UpdatePolicy:   UpdatePolicy{Mode: UpdateNever},
 	}
 }
 
 func (w *WinMowerRegistry) GetWinMower(platform Platform, ctx context.Context) (*WinMower, error) {
-	wm, err := w.GetCachedWinMower(platform, ctx)
+	wm, err := w.getWinMower(platform, ctx)
 	if err != nil {
 		return nil, err
 	}
-	if wm != nil {
+	if err := cache.Touch(w.slotDir(platform)); err != nil {
+		log.Warn("Failed to record winmower usage", "err", err)
+	}
+	return wm, nil
+}
+
+func (w *WinMowerRegistry) getWinMower(platform Platform, ctx context.Context) (*WinMower, error) {
+	var wm *WinMower
+	var err error
+	if w.BundleType == "" {
+		wm, err = w.GetCachedWinMower(platform, ctx)
+	} else {
+		if !platform.Info().MatchesBundleType(w.BundleType) {
+			return nil, fmt.Errorf("bundle type %s is not a bundle type of platform %s", w.BundleType, platform)
+		}
+		wm, err = readCachedWinMower(w.slotDir(platform))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if wm != nil && !w.UpdatePolicy.ShouldCheck(wm.lastChecked()) {
 		log.Debug("Using cached winmower")
 		return wm, nil
 	}
 
-	btypes, err := w.bundleRegistry.FetchBundleTypes(ctx)
+	latestType, latestBuild, err := w.fetchLatestBuild(platform, ctx)
 	if err != nil {
+		if wm != nil {
+			log.Warn("Failed to check for winmower update, using cached winmower", "err", err)
+			return wm, nil
+		}
 		return nil, err
 	}
-	log.Debugf("Found %d bundle types\n", len(btypes))
 
-	btypes = FilterBundleTypes(btypes, platform)
-	if len(btypes) == 0 {
-		return nil, fmt.Errorf("no bundle types found for platform %s", platform)
+	dir := w.slotDir(platform)
+	if wm != nil && wm.manifest != nil && wm.BuildId == latestBuild.Id {
+		log.Debug("Cached winmower is up to date", "build", wm.BuildId)
+		wm.manifest.CheckedAt = time.Now()
+		if err := cache.WriteManifest(dir, wm.manifest); err != nil {
+			log.Warn("Failed to update winmower cache manifest", "err", err)
+		}
+		return wm, nil
 	}
-	log.Debugf("Found %d bundle types for platform %s\n", len(btypes), platform)
-
-	// Endpoint returns them sorted by date (i think)
-	latestType := btypes[0]
-	log.Debugf("Latest bundle type: %s\n", latestType.Name)
+	if wm != nil {
+		log.Info("Updating winmower", "from", wm.BuildId, "to", latestBuild.Id)
+	}
+	return w.install(platform, latestType.Name, latestBuild, ctx)
+}
 
-	latestBuild, err := w.bundleRegistry.FetchLatestRelease(ctx, latestType.Name)
+// GetWinMowerBuild returns the given winmower build for platform, replacing
+// the cached winmower if it is a different build.
+func (w *WinMowerRegistry) GetWinMowerBuild(platform Platform, bundleType, buildId string, ctx context.Context) (*WinMower, error) {
+	wm, err := w.GetCachedWinMower(platform, ctx)
 	if err != nil {
 		return nil, err
 	}
-	log.Debugf("Latest build: %s\n", latestBuild.BlobUrl)
+	if wm == nil || wm.BuildId != buildId {
+		build, err := w.bundleRegistry.FetchRelease(ctx, bundleType, buildId)
+		if err != nil {
+			return nil, err
+		}
+		wm, err = w.install(platform, bundleType, build, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	dir := filepath.Join(w.CacheDir, platform.String())
-	req, err := http.NewRequestWithContext(ctx, "GET", latestBuild.BlobUrl, nil)
+	if err := cache.Touch(w.slotDir(platform)); err != nil {
+		log.Warn("Failed to record winmower usage", "err", err)
+	}
+	return wm, nil
+}
+
+// install downloads build as the cached winmower for platform.
+func (w *WinMowerRegistry) install(platform Platform, bundleType string, build *Build, ctx context.Context) (*WinMower, error) {
+	dir := w.slotDir(platform)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove cached winmower: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", build.BlobUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 	AddTifAuthHeaders(req)
-	log.Debug("Downloading and unpacking winmower...")
+	log.Debug("Downloading and unpacking winmower...", "build", build.Id)
 	err = ext.DownloadAndUnpack(req, dir)
 	if err != nil {
 		return nil, err
@@ -76,11 +140,67 @@ func (w *WinMowerRegistry) GetWinMower(platform Platform, ctx context.Context) (
 		return nil, err
 	}
 
+	checksums, err := cache.HashDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash winmower: %w", err)
+	}
+	now := time.Now()
+	manifest := &cache.Manifest{
+		Kind:         cache.KindWinMower,
+		Key:          filepath.Base(dir),
+		BuildId:      build.Id,
+		BundleType:   bundleType,
+		Platform:     platform.String(),
+		DownloadedAt: now,
+		CheckedAt:    now,
+		Checksums:    checksums,
+	}
+	if err := cache.WriteManifest(dir, manifest); err != nil {
+		return nil, fmt.Errorf("failed to write winmower cache manifest: %w", err)
+	}
+
 	return &WinMower{
-		Path: wmPath,
+		Path:       wmPath,
+		BuildId:    build.Id,
+		BundleType: bundleType,
+		manifest:   manifest,
 	}, nil
 }
 
+func (w *WinMowerRegistry) fetchLatestBuild(platform Platform, ctx context.Context) (*BundleType, *Build, error) {
+	if w.BundleType != "" {
+		build, err := w.bundleRegistry.FetchLatestRelease(ctx, w.BundleType)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &BundleType{Name: w.BundleType}, build, nil
+	}
+
+	btypes, err := w.bundleRegistry.FetchBundleTypes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("Found %d bundle types\n", len(btypes))
+
+	btypes = FilterBundleTypes(btypes, platform)
+	if len(btypes) == 0 {
+		return nil, nil, fmt.Errorf("no bundle types found for platform %s", platform)
+	}
+	log.Debugf("Found %d bundle types for platform %s\n", len(btypes), platform)
+
+	// Endpoint returns them sorted by date (i think)
+	latestType := btypes[0]
+	log.Debugf("Latest bundle type: %s\n", latestType.Name)
+
+	latestBuild, err := w.bundleRegistry.FetchLatestRelease(ctx, latestType.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("Latest build: %s\n", latestBuild.BlobUrl)
+
+	return &latestType, latestBuild, nil
+}
+
 func (w *WinMowerRegistry) GetCachedWinMower(platform Platform, ctx context.Context) (*WinMower, error) {
 	var wmDir string
 	err := filepath.WalkDir(w.CacheDir, func(path string, d fs.DirEntry, err error) error {
@@ -95,15 +215,49 @@ func (w *WinMowerRegistry) GetCachedWinMower(platform Platform, ctx context.Cont
 	if wmDir == "" {
 		return nil, nil
 	}
+	return readCachedWinMower(wmDir)
+}
 
+// readCachedWinMower returns the winmower cached in wmDir, or nil if there is
+// none.
+func readCachedWinMower(wmDir string) (*WinMower, error) {
+	if _, err := os.Stat(wmDir); os.IsNotExist(err) {
+		return nil, nil
+	}
 	path, err := locateWinMowerExecutable(wmDir)
 	if err != nil {
 		return nil, err
 	}
 
-	return &WinMower{
+	wm := &WinMower{
 		Path: path,
-	}, nil
+	}
+	manifest, err := cache.ReadManifest(wmDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		wm.BuildId = manifest.BuildId
+		wm.BundleType = manifest.BundleType
+		wm.manifest = manifest
+	}
+	return wm, nil
+}
+
+// slotDir returns the cache directory of the winmower of platform, which
+// depends on BundleType.
+func (w *WinMowerRegistry) slotDir(platform Platform) string {
+	if w.BundleType == "" {
+		return filepath.Join(w.CacheDir, platform.String())
+	}
+	return filepath.Join(w.CacheDir, platform.String()+"@"+w.BundleType)
+}
+
+func (wm *WinMower) lastChecked() time.Time {
+	if wm.manifest == nil {
+		return time.Time{}
+	}
+	return wm.manifest.CheckedAt
 }
 
 func locateWinMowerExecutable(dir string) (string, error) {
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// AutoAddress makes ResolveAddress pick a free port on the loopback interface.
+const AutoAddress = "auto"
+
+// ResolveAddress returns the host:port address a process should listen on.
+// A fixed address is checked to be free so a busy port fails before anything
+// is started.
+func ResolveAddress(spec string) (string, error) {
+	if spec == AutoAddress {
+		return freeAddress("127.0.0.1")
+	}
+
+	host, port, err := net.SplitHostPort(spec)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", spec, err)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid port in address %q", spec)
+	}
+	if host == "" {
+		spec = net.JoinHostPort("127.0.0.1", port)
+	}
+
+	l, err := net.Listen("tcp", spec)
+	if err != nil {
+		return "", fmt.Errorf("address %s is already in use, close the process using it or pick another address: %w", spec, err)
+	}
+	l.Close()
+	return spec, nil
+}
+
+func freeAddress(host string) (string, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+// ExpandArgs replaces {address}, {host} and {port} in args with the parts of
+// address.
+func ExpandArgs(args []string, address string) []string {
+	host, port, _ := net.SplitHostPort(address)
+	r := strings.NewReplacer("{address}", address, "{host}", host, "{port}", port)
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = r.Replace(arg)
+	}
+	return expanded
+}
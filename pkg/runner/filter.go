@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+)
+
+const defaultHighlightColor = "#f59e0b"
+
+// FilterRules decide which lines of a process are logged and how they look.
+type FilterRules struct {
+	// ModuleLevels are the minimum levels per module. "*" applies to modules
+	// without a level of their own.
+	ModuleLevels map[string]string `mapstructure:"moduleLevels"`
+	// Include, when not empty, only lets lines matching one of the patterns
+	// through. Exclude drops lines matching any of its patterns.
+	Include    []string    `mapstructure:"include"`
+	Exclude    []string    `mapstructure:"exclude"`
+	Highlights []Highlight `mapstructure:"highlights"`
+	// Collapse logs identical consecutive lines once with a repeat count.
+	Collapse bool `mapstructure:"collapse"`
+}
+
+type Highlight struct {
+	Pattern string `mapstructure:"pattern"`
+	Color   string `mapstructure:"color"`
+}
+
+// Filter applies FilterRules. The rules can be changed while processes are
+// logging through it. A nil Filter lets everything through.
+type Filter struct {
+	mu           sync.RWMutex
+	initial      FilterRules
+	moduleLevels map[string]log.Level
+	include      []*regexp.Regexp
+	exclude      []*regexp.Regexp
+	highlights   []highlight
+	collapse     bool
+}
+
+type highlight struct {
+	re    *regexp.Regexp
+	color string
+	style lipgloss.Style
+}
+
+func NewFilter(rules FilterRules) (*Filter, error) {
+	f := &Filter{initial: rules}
+	if err := f.Reset(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reset replaces the rules with the ones the filter was created with.
+func (f *Filter) Reset() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.moduleLevels = make(map[string]log.Level)
+	f.include, f.exclude, f.highlights = nil, nil, nil
+	f.collapse = f.initial.Collapse
+
+	for module, level := range f.initial.ModuleLevels {
+		if err := f.setModuleLevel(module, level); err != nil {
+			return err
+		}
+	}
+	for _, p := range f.initial.Include {
+		if err := f.addPattern(&f.include, p); err != nil {
+			return err
+		}
+	}
+	for _, p := range f.initial.Exclude {
+		if err := f.addPattern(&f.exclude, p); err != nil {
+			return err
+		}
+	}
+	for _, h := range f.initial.Highlights {
+		if err := f.addHighlight(h.Pattern, h.Color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Filter) SetModuleLevel(module, level string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.setModuleLevel(module, level)
+}
+
+func (f *Filter) setModuleLevel(module, level string) error {
+	l, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid level %q for module %s", level, module)
+	}
+	f.moduleLevels[module] = l
+	return nil
+}
+
+func (f *Filter) Include(pattern string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addPattern(&f.include, pattern)
+}
+
+func (f *Filter) Exclude(pattern string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addPattern(&f.exclude, pattern)
+}
+
+func (f *Filter) addPattern(list *[]*regexp.Regexp, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	*list = append(*list, re)
+	return nil
+}
+
+func (f *Filter) Highlight(pattern, color string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addHighlight(pattern, color)
+}
+
+func (f *Filter) addHighlight(pattern, color string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid highlight pattern %q: %w", pattern, err)
+	}
+	if color == "" {
+		color = defaultHighlightColor
+	}
+	f.highlights = append(f.highlights, highlight{
+		re:    re,
+		color: color,
+		style: lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true),
+	})
+	return nil
+}
+
+func (f *Filter) SetCollapse(collapse bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.collapse = collapse
+}
+
+// Allow reports whether a line of module at level is logged.
+func (f *Filter) Allow(module string, level log.Level, line string) bool {
+	if f == nil {
+		return true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	min, ok := f.moduleLevels[module]
+	if !ok {
+		min, ok = f.moduleLevels["*"]
+	}
+	if ok && level < min {
+		return false
+	}
+
+	if len(f.include) > 0 && !matchesAny(f.include, line) {
+		return false
+	}
+	return !matchesAny(f.exclude, line)
+}
+
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decorate colors the parts of line matching a highlight pattern.
+func (f *Filter) Decorate(line string) string {
+	if f == nil {
+		return line
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, h := range f.highlights {
+		line = h.re.ReplaceAllStringFunc(line, func(s string) string {
+			return h.style.Render(s)
+		})
+	}
+	return line
+}
+
+func (f *Filter) Collapses() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.collapse
+}
+
+// String describes the current rules.
+func (f *Filter) String() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var b strings.Builder
+	modules := make([]string, 0, len(f.moduleLevels))
+	for m := range f.moduleLevels {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	for _, m := range modules {
+		fmt.Fprintf(&b, "  level %s %s\n", m, f.moduleLevels[m])
+	}
+	for _, re := range f.include {
+		fmt.Fprintf(&b, "  include %s\n", re)
+	}
+	for _, re := range f.exclude {
+		fmt.Fprintf(&b, "  exclude %s\n", re)
+	}
+	for _, h := range f.highlights {
+		fmt.Fprintf(&b, "  highlight %s %s\n", h.re, h.color)
+	}
+	fmt.Fprintf(&b, "  collapse %t\n", f.collapse)
+	return b.String()
+}
+
+// collapser counts identical consecutive lines of one logger.
+type collapser struct {
+	last    string
+	repeats int
+}
+
+// seen reports whether line should be logged and, when it ends a run of
+// repeats, how many lines were left out before it.
+func (c *collapser) seen(line string, collapse bool) (show bool, repeats int) {
+	if collapse && line == c.last {
+		c.repeats++
+		return false, 0
+	}
+	repeats = c.repeats
+	c.last = line
+	c.repeats = 0
+	return true, repeats
+}
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LineBuffer keeps the last lines written to it.
+type LineBuffer struct {
+	mu      sync.Mutex
+	size    int
+	lines   []string
+	next    int
+	partial []byte
+}
+
+func NewLineBuffer(size int) *LineBuffer {
+	return &LineBuffer{
+		size:  size,
+		lines: make([]string, 0, size),
+	}
+}
+
+func (b *LineBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.partial = append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(b.partial, '\n')
+		if i < 0 {
+			break
+		}
+		b.add(string(bytes.TrimSuffix(b.partial[:i], []byte("\r"))))
+		b.partial = b.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (b *LineBuffer) add(line string) {
+	if len(b.lines) < b.size {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % b.size
+}
+
+// Lines returns the buffered lines, oldest first, including an unfinished
+// last line.
+func (b *LineBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lines := make([]string, 0, len(b.lines)+1)
+	lines = append(lines, b.lines[b.next:]...)
+	lines = append(lines, b.lines[:b.next]...)
+	if len(b.partial) > 0 {
+		lines = append(lines, string(b.partial))
+	}
+	return lines
+}
+
+// Reset forgets the buffered lines.
+func (b *LineBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines = b.lines[:0]
+	b.next = 0
+	b.partial = nil
+}
@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusNotStarted Status = "not started"
+	StatusRunning    Status = "running"
+	StatusExited     Status = "exited"
+	StatusStopped    Status = "stopped"
+)
+
+const (
+	defaultGracePeriod = 5 * time.Second
+	// killTimeout is how long a killed process tree has to exit.
+	killTimeout = 5 * time.Second
+)
+
+// Runner is a process that can be started, waited on and stopped. Runners
+// can be started again once they have exited.
+type Runner interface {
+	Start(ctx context.Context) error
+	Wait() error
+	Stop() error
+	Status() Status
+	// ExitInfo returns nil until the process has exited.
+	ExitInfo() *ExitInfo
+}
+
+type ExitInfo struct {
+	Code     int
+	Err      error
+	Started  time.Time
+	ExitedAt time.Time
+}
+
+func (e *ExitInfo) String() string {
+	return fmt.Sprintf("exit code %d after %s", e.Code, e.ExitedAt.Sub(e.Started).Round(time.Millisecond))
+}
+
+// process implements Runner around the exec.Cmd returned by newCmd. Stopping
+// first asks the whole process tree to close and kills it once the grace
+// period has passed. Without a grace period the tree is killed right away.
+type process struct {
+	name        string
+	newCmd      func() *exec.Cmd
+	gracePeriod time.Duration
+
+	mu       sync.Mutex
+	watchers []io.Writer
+	cmd      *exec.Cmd
+	status   Status
+	stopping bool
+	started  time.Time
+	exit     *ExitInfo
+	done     chan struct{}
+}
+
+func newProcess(name string, newCmd func() *exec.Cmd) *process {
+	return &process{
+		name:        name,
+		newCmd:      newCmd,
+		gracePeriod: defaultGracePeriod,
+		status:      StatusNotStarted,
+	}
+}
+
+// newConsoleProcess returns a process for a windowless console program.
+// taskkill can only ask programs with a window to close, so these are killed
+// when stopped.
+func newConsoleProcess(name string, newCmd func() *exec.Cmd) *process {
+	p := newProcess(name, newCmd)
+	p.gracePeriod = 0
+	return p
+}
+
+// Start starts the process. It keeps running until it exits or is stopped;
+// cancelling ctx does not stop it, so whoever started it decides the order
+// processes are stopped in.
+func (p *process) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status == StatusRunning {
+		return fmt.Errorf("%s is already running", p.name)
+	}
+
+	cmd := p.newCmd()
+	if len(p.watchers) > 0 {
+		cmd.Stdout = teeWriter(cmd.Stdout, p.watchers)
+		cmd.Stderr = teeWriter(cmd.Stderr, p.watchers)
+	}
+	// In its own process group Ctrl+C in the launcher's console does not
+	// reach the process, which is stopped by Stop instead.
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", p.name, err)
+	}
+
+	done := make(chan struct{})
+	p.cmd = cmd
+	p.status = StatusRunning
+	p.stopping = false
+	p.started = time.Now()
+	p.exit = nil
+	p.done = done
+
+	go p.wait(cmd, done)
+	return nil
+}
+
+func (p *process) wait(cmd *exec.Cmd, done chan struct{}) {
+	err := cmd.Wait()
+
+	p.mu.Lock()
+	p.exit = &ExitInfo{
+		Code:     cmd.ProcessState.ExitCode(),
+		Err:      err,
+		Started:  p.started,
+		ExitedAt: time.Now(),
+	}
+	if p.stopping {
+		p.status = StatusStopped
+	} else {
+		p.status = StatusExited
+	}
+	p.mu.Unlock()
+	close(done)
+}
+
+// Wait blocks until the process exits and returns its error.
+func (p *process) Wait() error {
+	p.mu.Lock()
+	done := p.done
+	p.mu.Unlock()
+	if done == nil {
+		return fmt.Errorf("%s was never started", p.name)
+	}
+
+	<-done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exit.Err
+}
+
+// Stop closes the process tree gracefully and kills it if it is still
+// running after the grace period. Stopping a process that is not running
+// does nothing.
+func (p *process) Stop() error {
+	p.mu.Lock()
+	if p.status != StatusRunning || p.stopping {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopping = true
+	pid := p.cmd.Process.Pid
+	done := p.done
+	p.mu.Unlock()
+
+	if p.gracePeriod > 0 {
+		killProcessTree(pid, false)
+		select {
+		case <-done:
+			return nil
+		case <-time.After(p.gracePeriod):
+		}
+	}
+
+	if err := killProcessTree(pid, true); err != nil {
+		return fmt.Errorf("failed to kill %s: %w", p.name, err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(killTimeout):
+		return fmt.Errorf("%s did not exit after being killed", p.name)
+	}
+}
+
+func (p *process) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *process) ExitInfo() *ExitInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exit
+}
+
+// exited returns a channel that is closed when the current run of the
+// process exits.
+func (p *process) exited() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+// Watch copies the output of the process to w from the next start on.
+func (p *process) Watch(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchers = append(p.watchers, w)
+}
+
+func teeWriter(w io.Writer, watchers []io.Writer) io.Writer {
+	if w == nil {
+		return io.MultiWriter(watchers...)
+	}
+	return io.MultiWriter(append([]io.Writer{w}, watchers...)...)
+}
+
+func (p *process) Pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// killProcessTree asks pid and its children to close, or kills them when
+// force is set.
+func killProcessTree(pid int, force bool) error {
+	args := []string{"/T", "/PID", strconv.Itoa(pid)}
+	if force {
+		args = append(args, "/F")
+	}
+	out, err := exec.Command("taskkill", args...).CombinedOutput()
+	if err != nil {
+		return errors.Join(err, errors.New(string(out)))
+	}
+	return nil
+}
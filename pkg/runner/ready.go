@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const probeInterval = 250 * time.Millisecond
+
+// Probe reports when a started runner is ready to be used by the processes
+// that depend on it.
+type Probe interface {
+	// Wait blocks until r is ready, r exits or ctx is done.
+	Wait(ctx context.Context, r Runner) error
+	String() string
+}
+
+// WaitReady waits up to timeout for probe to report r as ready.
+func WaitReady(ctx context.Context, name string, r Runner, probe Probe, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := probe.Wait(ctx, r)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s did not become ready within %s (%s): %w", name, timeout, probe, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s did not become ready (%s): %w", name, probe, err)
+	}
+	return nil
+}
+
+// exited returns an error when r is no longer running.
+func exited(r Runner) error {
+	if r.Status() == StatusRunning {
+		return nil
+	}
+	if info := r.ExitInfo(); info != nil {
+		return fmt.Errorf("process exited with %s", info)
+	}
+	return errors.New("process is not running")
+}
+
+// TCPProbe is ready once a connection to Address is accepted.
+type TCPProbe struct {
+	Address string
+}
+
+func (p *TCPProbe) Wait(ctx context.Context, r Runner) error {
+	var dialer net.Dialer
+	var lastErr error
+	for {
+		if err := exited(r); err != nil {
+			return err
+		}
+
+		dialCtx, cancel := context.WithTimeout(ctx, time.Second)
+		conn, err := dialer.DialContext(dialCtx, "tcp", p.Address)
+		cancel()
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %w", ctx.Err(), lastErr)
+			}
+			return ctx.Err()
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+func (p *TCPProbe) String() string {
+	return "tcp " + p.Address
+}
+
+// LogProbe is ready once a line of output matches Pattern. It must be
+// attached to the runner's output before the runner is started.
+type LogProbe struct {
+	Pattern *regexp.Regexp
+
+	mu      sync.Mutex
+	partial []byte
+	matched chan struct{}
+}
+
+func NewLogProbe(pattern *regexp.Regexp) *LogProbe {
+	return &LogProbe{
+		Pattern: pattern,
+		matched: make(chan struct{}),
+	}
+}
+
+func (p *LogProbe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.matched:
+		return len(b), nil
+	default:
+	}
+
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := p.partial[:i]
+		p.partial = p.partial[i+1:]
+		if p.Pattern.Match(line) {
+			p.partial = nil
+			close(p.matched)
+			break
+		}
+	}
+	return len(b), nil
+}
+
+// Reset forgets an earlier match so the probe can be used again after a
+// restart.
+func (p *LogProbe) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.partial = nil
+	select {
+	case <-p.matched:
+		p.matched = make(chan struct{})
+	default:
+	}
+}
+
+func (p *LogProbe) Wait(ctx context.Context, r Runner) error {
+	p.mu.Lock()
+	matched := p.matched
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-matched:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := exited(r); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *LogProbe) String() string {
+	return fmt.Sprintf("log %q", p.Pattern)
+}
+
+// AliveProbe is ready once the process has kept running for Duration.
+type AliveProbe struct {
+	Duration time.Duration
+}
+
+func (p *AliveProbe) Wait(ctx context.Context, r Runner) error {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	deadline := time.After(p.Duration)
+	for {
+		select {
+		case <-deadline:
+			return exited(r)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := exited(r); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *AliveProbe) String() string {
+	return fmt.Sprintf("alive for %s", p.Duration)
+}
+
+// watcher is implemented by runners whose output can be observed.
+type watcher interface {
+	Watch(w io.Writer)
+}
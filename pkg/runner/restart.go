@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// RestartPolicy decides how a runner that exits on its own is restarted.
+// A zero policy only reports the exit.
+type RestartPolicy struct {
+	MaxRestarts int
+	// Backoff is the wait before the first restart. It doubles for every
+	// following restart up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (p RestartPolicy) backoff(restart int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < restart; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
+}
+
+var errClosed = errors.New("supervisor is shut down")
+
+type restartSpec struct {
+	policy RestartPolicy
+	// after runs once the runner is ready again.
+	after func(ctx context.Context) error
+}
+
+// recentLiner is implemented by runners that keep their last lines of output.
+type recentLiner interface {
+	RecentLines() []string
+}
+
+// resetter is implemented by probes that have to forget an earlier start.
+type resetter interface {
+	Reset()
+}
+
+// monitor reports every time the named runner exits on its own and restarts
+// it as long as policy allows.
+func (s *Supervisor) monitor(ctx context.Context, name string, r Runner, spec restartSpec) {
+	restarts := 0
+	for {
+		r.Wait()
+		if ctx.Err() != nil || r.Status() != StatusExited {
+			return
+		}
+
+		info := r.ExitInfo()
+		keyvals := []any{"name", name, "exitCode", info.Code, "ranFor", info.ExitedAt.Sub(info.Started).Round(time.Millisecond)}
+		if rl, ok := r.(recentLiner); ok {
+			if lines := rl.RecentLines(); len(lines) > 0 {
+				keyvals = append(keyvals, "lastLines", "\n"+strings.Join(lines, "\n"))
+			}
+		}
+		log.Error("Process exited unexpectedly", keyvals...)
+
+		if !s.restartWithBackoff(ctx, name, r, spec.policy, &restarts) {
+			return
+		}
+		if spec.after != nil {
+			if err := spec.after(ctx); err != nil {
+				log.Error("Failed to run after restart", "name", name, "err", err)
+			}
+		}
+	}
+}
+
+// restartWithBackoff restarts the named runner, trying again as long as the
+// policy allows. It reports whether the runner is running again.
+func (s *Supervisor) restartWithBackoff(ctx context.Context, name string, r Runner, policy RestartPolicy, restarts *int) bool {
+	for {
+		if *restarts >= policy.MaxRestarts {
+			if policy.MaxRestarts > 0 {
+				log.Error("Giving up restarting process", "name", name, "restarts", *restarts)
+			}
+			return false
+		}
+		*restarts++
+
+		wait := policy.backoff(*restarts)
+		log.Warn("Restarting process", "name", name, "attempt", *restarts, "of", policy.MaxRestarts, "in", wait)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+
+		err := s.restart(ctx, name, r)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, errClosed) || ctx.Err() != nil {
+			return false
+		}
+		log.Error("Failed to restart process", "name", name, "err", err)
+		// A restart that started the runner but timed out waiting for it to
+		// become ready leaves it running.
+		r.Stop()
+	}
+}
+
+func (s *Supervisor) restart(ctx context.Context, name string, r Runner) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errClosed
+	}
+	ready, hasProbe := s.probes[name]
+	if rs, ok := ready.probe.(resetter); hasProbe && ok {
+		rs.Reset()
+	}
+	err := r.Start(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if hasProbe {
+		return WaitReady(ctx, name, r, ready.probe, ready.timeout)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTestCasePattern matches the line TifConsole starts a test case with,
+// such as "Running test case: Mow work area". It needs a name group.
+const DefaultTestCasePattern = `^\s*(?:Running|Starting|Executing)\s+(?:test\s*case|test|case)\s*:?\s+(?P<name>.+?)\s*$`
+
+// DefaultTestStepPattern matches a finished test step, such as
+// "Start mower ... PASSED (1.2s)" or "Check charging [FAILED] - no contact".
+// The status follows a dot leader or is in brackets, so other output ending
+// in a status word, such as "Connecting to 127.0.0.1:4250: OK", is not a step.
+// It needs the groups name and status and may use duration and message.
+const DefaultTestStepPattern = `^\s*(?:Step\s+\d+\s*:\s*)?(?P<name>\S.*?)\s*(?:\.{2,}\s*\[?|\s\[)(?P<status>PASS(?:ED)?|OK|FAIL(?:ED|URE)?|ERROR|SKIP(?:PED)?)\]?(?:\s*\((?P<duration>[\d.]+\s*(?:ms|s|m))\))?(?:\s*[-:]\s*(?P<message>.+))?\s*$`
+
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "passed"
+	TestFailed  TestStatus = "failed"
+	TestSkipped TestStatus = "skipped"
+)
+
+// TestResults are the results of one run of a test bundle.
+type TestResults struct {
+	Bundle   string        `json:"bundle"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+	ExitCode int           `json:"exitCode"`
+	Cases    []*TestCase   `json:"cases"`
+}
+
+type TestCase struct {
+	Name     string        `json:"name"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Status   TestStatus    `json:"status"`
+	Steps    []*TestStep   `json:"steps"`
+}
+
+type TestStep struct {
+	Name     string        `json:"name"`
+	Status   TestStatus    `json:"status"`
+	Duration time.Duration `json:"duration"`
+	// Message explains a failure. Indented lines following a failed step are
+	// added to it.
+	Message string `json:"message,omitempty"`
+}
+
+// Counts returns how many steps passed, failed and were skipped.
+func (r *TestResults) Counts() (passed, failed, skipped int) {
+	for _, c := range r.Cases {
+		for _, s := range c.Steps {
+			switch s.Status {
+			case TestPassed:
+				passed++
+			case TestFailed:
+				failed++
+			case TestSkipped:
+				skipped++
+			}
+		}
+	}
+	return passed, failed, skipped
+}
+
+func (r *TestResults) Failed() bool {
+	_, failed, _ := r.Counts()
+	return failed > 0 || r.ExitCode != 0
+}
+
+type TestResultParser struct {
+	casePattern *regexp.Regexp
+	stepPattern *regexp.Regexp
+}
+
+func NewTestResultParser(casePattern, stepPattern string) (*TestResultParser, error) {
+	caseRe, err := regexp.Compile(casePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid test case pattern: %w", err)
+	}
+	if caseRe.SubexpIndex("name") < 0 {
+		return nil, fmt.Errorf("test case pattern has no name group")
+	}
+	stepRe, err := regexp.Compile(stepPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid test step pattern: %w", err)
+	}
+	if stepRe.SubexpIndex("name") < 0 || stepRe.SubexpIndex("status") < 0 {
+		return nil, fmt.Errorf("test step pattern needs name and status groups")
+	}
+	return &TestResultParser{casePattern: caseRe, stepPattern: stepRe}, nil
+}
+
+// resultCollector builds TestResults from the output of one TifConsole run.
+type resultCollector struct {
+	parser    *TestResultParser
+	mu        sync.Mutex
+	results   *TestResults
+	current   *TestCase
+	lastStep  *TestStep
+	lastEvent time.Time
+	partial   string
+}
+
+func (p *TestResultParser) collector(bundle string) *resultCollector {
+	now := time.Now()
+	return &resultCollector{
+		parser:    p,
+		results:   &TestResults{Bundle: bundle, Started: now},
+		lastEvent: now,
+	}
+}
+
+func (c *resultCollector) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lines := strings.Split(c.partial+string(b), "\n")
+	c.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		c.line(strings.TrimSuffix(line, "\r"), time.Now())
+	}
+	return len(b), nil
+}
+
+func (c *resultCollector) line(line string, now time.Time) {
+	if m := c.parser.casePattern.FindStringSubmatch(line); m != nil {
+		c.current = &TestCase{
+			Name:    m[c.parser.casePattern.SubexpIndex("name")],
+			Started: now,
+		}
+		c.results.Cases = append(c.results.Cases, c.current)
+		c.lastStep = nil
+		c.lastEvent = now
+		return
+	}
+
+	if m := c.parser.stepPattern.FindStringSubmatch(line); m != nil {
+		step := &TestStep{
+			Name:     m[c.parser.stepPattern.SubexpIndex("name")],
+			Status:   parseTestStatus(m[c.parser.stepPattern.SubexpIndex("status")]),
+			Duration: now.Sub(c.lastEvent),
+		}
+		if i := c.parser.stepPattern.SubexpIndex("duration"); i >= 0 && m[i] != "" {
+			if d, err := time.ParseDuration(strings.ReplaceAll(m[i], " ", "")); err == nil {
+				step.Duration = d
+			}
+		}
+		if i := c.parser.stepPattern.SubexpIndex("message"); i >= 0 {
+			step.Message = m[i]
+		}
+		if c.current == nil {
+			// Steps before any test case belong to the bundle itself.
+			c.current = &TestCase{Name: c.results.Bundle, Started: c.results.Started}
+			c.results.Cases = append(c.results.Cases, c.current)
+		}
+		c.current.Steps = append(c.current.Steps, step)
+		c.lastStep = step
+		c.lastEvent = now
+		return
+	}
+
+	if c.lastStep != nil && c.lastStep.Status == TestFailed && strings.TrimSpace(line) != "" &&
+		(strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+		if c.lastStep.Message != "" {
+			c.lastStep.Message += "\n"
+		}
+		c.lastStep.Message += strings.TrimSpace(line)
+	}
+}
+
+func parseTestStatus(s string) TestStatus {
+	switch s := strings.ToUpper(s); {
+	case strings.HasPrefix(s, "PASS"), s == "OK":
+		return TestPassed
+	case strings.HasPrefix(s, "SKIP"):
+		return TestSkipped
+	default:
+		return TestFailed
+	}
+}
+
+// finish completes the results once TifConsole exited with info.
+func (c *resultCollector) finish(info *ExitInfo) *TestResults {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.partial != "" {
+		c.line(c.partial, time.Now())
+		c.partial = ""
+	}
+
+	r := c.results
+	r.Finished = time.Now()
+	if info != nil {
+		r.ExitCode = info.Code
+		r.Finished = info.ExitedAt
+	}
+	r.Duration = r.Finished.Sub(r.Started)
+
+	if len(r.Cases) == 0 {
+		// Without recognised output the exit code is all there is to report.
+		step := &TestStep{Name: "run", Status: TestPassed, Duration: r.Duration}
+		if r.ExitCode != 0 {
+			step.Status = TestFailed
+			step.Message = fmt.Sprintf("TifConsole exited with code %d", r.ExitCode)
+		}
+		r.Cases = append(r.Cases, &TestCase{Name: r.Bundle, Started: r.Started, Steps: []*TestStep{step}})
+	}
+
+	for i, tc := range r.Cases {
+		end := r.Finished
+		if i+1 < len(r.Cases) {
+			end = r.Cases[i+1].Started
+		}
+		tc.Duration = end.Sub(tc.Started)
+		tc.Status = TestPassed
+		if len(tc.Steps) > 0 {
+			tc.Status = TestSkipped
+		}
+		for _, s := range tc.Steps {
+			if s.Status == TestFailed {
+				tc.Status = TestFailed
+				break
+			}
+			if s.Status == TestPassed {
+				tc.Status = TestPassed
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func collectFixture(t *testing.T) *TestResults {
+	t.Helper()
+	parser, err := NewTestResultParser(DefaultTestCasePattern, DefaultTestStepPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(filepath.Join("testdata", "tifconsole-output.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := parser.collector("Mowing")
+	if _, err := c.Write(output); err != nil {
+		t.Fatal(err)
+	}
+	started := c.results.Started
+	return c.finish(&ExitInfo{Code: 1, Started: started, ExitedAt: started.Add(20 * time.Second)})
+}
+
+func TestResultCollector(t *testing.T) {
+	r := collectFixture(t)
+
+	type step struct {
+		name     string
+		status   TestStatus
+		duration time.Duration
+	}
+	want := map[string][]step{
+		"Start mowing": {
+			{"Wake up mower", TestPassed, 800 * time.Millisecond},
+			{"Start mower", TestPassed, 1200 * time.Millisecond},
+		},
+		"Charging": {
+			{"Drive to charging station", TestPassed, 12500 * time.Millisecond},
+			{"Check charging", TestFailed, -1},
+			{"Verify battery level", TestSkipped, -1},
+		},
+	}
+	if len(r.Cases) != len(want) {
+		t.Fatalf("got %d test cases, want %d", len(r.Cases), len(want))
+	}
+	for _, tc := range r.Cases {
+		steps, ok := want[tc.Name]
+		if !ok {
+			t.Errorf("unexpected test case %q", tc.Name)
+			continue
+		}
+		if len(tc.Steps) != len(steps) {
+			t.Errorf("%s: got %d steps, want %d", tc.Name, len(tc.Steps), len(steps))
+			continue
+		}
+		for i, s := range tc.Steps {
+			w := steps[i]
+			if s.Name != w.name || s.Status != w.status {
+				t.Errorf("%s: step %d is %q %s, want %q %s", tc.Name, i, s.Name, s.Status, w.name, w.status)
+			}
+			if w.duration >= 0 && s.Duration != w.duration {
+				t.Errorf("%s: step %q took %s, want %s", tc.Name, s.Name, s.Duration, w.duration)
+			}
+		}
+	}
+
+	failed := r.Cases[1].Steps[1]
+	wantMessage := "no contact\nExpected charging current above 0.5 A\nMeasured 0.0 A"
+	if failed.Message != wantMessage {
+		t.Errorf("failure message is %q, want %q", failed.Message, wantMessage)
+	}
+	if r.Cases[0].Status != TestPassed || r.Cases[1].Status != TestFailed {
+		t.Errorf("case statuses are %s and %s, want passed and failed", r.Cases[0].Status, r.Cases[1].Status)
+	}
+	if passed, failed, skipped := r.Counts(); passed != 3 || failed != 1 || skipped != 1 {
+		t.Errorf("counts are %d passed, %d failed, %d skipped, want 3, 1, 1", passed, failed, skipped)
+	}
+	if r.ExitCode != 1 || r.Duration != 20*time.Second {
+		t.Errorf("exit code %d after %s, want 1 after 20s", r.ExitCode, r.Duration)
+	}
+}
+
+func TestResultCollectorWithoutSteps(t *testing.T) {
+	parser, err := NewTestResultParser(DefaultTestCasePattern, DefaultTestStepPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := parser.collector("Mowing")
+	c.Write([]byte("Connecting to 127.0.0.1:4250: OK\nStatus: ERROR\n"))
+	r := c.finish(&ExitInfo{Code: 2, ExitedAt: time.Now()})
+
+	if len(r.Cases) != 1 || len(r.Cases[0].Steps) != 1 {
+		t.Fatalf("got %d test cases, want only the run", len(r.Cases))
+	}
+	if s := r.Cases[0].Steps[0]; s.Name != "run" || s.Status != TestFailed {
+		t.Errorf("step is %q %s, want run failed", s.Name, s.Status)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := collectFixture(t)
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("report does not start with the XML header")
+	}
+
+	var got junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Mowing" || got.Tests != 5 || got.Failures != 1 || got.Skipped != 1 || got.Time != "20.000" {
+		t.Errorf("suites are %q with %d tests, %d failures, %d skipped in %s, want Mowing with 5, 1, 1 in 20.000",
+			got.Name, got.Tests, got.Failures, got.Skipped, got.Time)
+	}
+	if len(got.Suites) != 2 {
+		t.Fatalf("got %d suites, want 2", len(got.Suites))
+	}
+
+	charging := got.Suites[1]
+	if charging.Name != "Charging" || charging.Tests != 3 || charging.Failures != 1 || charging.Skipped != 1 {
+		t.Errorf("suite is %q with %d tests, %d failures, %d skipped, want Charging with 3, 1, 1",
+			charging.Name, charging.Tests, charging.Failures, charging.Skipped)
+	}
+	tc := charging.Cases[1]
+	if tc.Name != "Check charging" || tc.ClassName != "Mowing.Charging" || tc.Failure == nil {
+		t.Fatalf("case is %q in %q, want failed Check charging in Mowing.Charging", tc.Name, tc.ClassName)
+	}
+	if tc.Failure.Message != "no contact" {
+		t.Errorf("failure message is %q, want no contact", tc.Failure.Message)
+	}
+	if charging.Cases[0].Time != "12.500" {
+		t.Errorf("case time is %s, want 12.500", charging.Cases[0].Time)
+	}
+	if charging.Cases[2].Skipped == nil {
+		t.Errorf("%s is not skipped", charging.Cases[2].Name)
+	}
+}
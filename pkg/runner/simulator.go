@@ -1,16 +1,187 @@
 package runner
 
-import "os/exec"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 
-func LaunchSimulator(simPath string, mapPath string) error {
+	"github.com/charmbracelet/log"
+)
+
+type SimulatorRunner struct {
+	*process
+	path    string
+	args    []string
+	logFile string
+	logger  io.Writer
+}
+
+type SimulatorLogger struct {
+	logger *log.Logger
+}
+
+// NewSimulatorRunner returns a runner of the simulator that writes its
+// output, and the log file it was told to write with -logFile, to logger.
+func NewSimulatorRunner(simPath string, args []string, logger io.Writer) *SimulatorRunner {
+	r := &SimulatorRunner{
+		path:    simPath,
+		args:    args,
+		logFile: argValue(args, "-logFile"),
+		logger:  logger,
+	}
+	r.process = newProcess("simulator", r.command)
+	return r
+}
+
+func (r *SimulatorRunner) command() *exec.Cmd {
+	cmd := exec.Command(r.path, r.args...)
+	cmd.Stdout = r.logger
+	cmd.Stderr = r.logger
+	return cmd
+}
+
+func (r *SimulatorRunner) Start(ctx context.Context) error {
+	if r.logFile != "" {
+		// A log left by an earlier start would be streamed again.
+		os.Remove(r.logFile)
+	}
+	if err := r.process.Start(ctx); err != nil {
+		return err
+	}
+
+	if r.logFile != "" {
+		done := r.exited()
+		tailCtx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-done
+			cancel()
+		}()
+		// The log file is output of the simulator too, so it goes to the
+		// watchers as well.
+		r.mu.Lock()
+		w := teeWriter(r.logger, r.watchers)
+		r.mu.Unlock()
+		go TailFile(tailCtx, r.logFile, w)
+	}
+	return nil
+}
+
+func argValue(args []string, name string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == name {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func NewSimulatorLogger(logger *log.Logger) *SimulatorLogger {
+	return &SimulatorLogger{
+		logger: logger,
+	}
+}
+
+func (l *SimulatorLogger) Write(bytes []byte) (int, error) {
+	str := strings.TrimSuffix(string(bytes), "\n")
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case line == "":
+		case strings.Contains(line, "Exception"), strings.HasPrefix(line, "Error"):
+			l.logger.Error(line)
+		case strings.HasPrefix(line, "Warning"):
+			l.logger.Warn(line)
+		default:
+			l.logger.Info(line)
+		}
+	}
+	return len(bytes), nil
+}
+
+// SimulatorOptions are the arguments the simulator is started with besides
+// the garden map.
+type SimulatorOptions struct {
+	Log          bool    `json:"log"`
+	TimeScale    float64 `json:"timeScale"`
+	ScreenWidth  int     `json:"screenWidth"`
+	ScreenHeight int     `json:"screenHeight"`
+	QualityLevel int     `json:"qualityLevel"`
+	// LogFile is where the simulator writes its Unity log when Log is set.
+	LogFile string `json:"logFile,omitempty"`
+	// ExtraArgs are appended to the command line as they are.
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+}
+
+func DefaultSimulatorOptions() SimulatorOptions {
+	return SimulatorOptions{
+		Log:          false,
+		TimeScale:    1,
+		ScreenWidth:  1280,
+		ScreenHeight: 720,
+		QualityLevel: 6,
+	}
+}
+
+// Set sets the option of the simulator argument name, without the leading
+// dash. Arguments without an option of their own are added to ExtraArgs.
+func (o *SimulatorOptions) Set(name, value string) error {
+	name = strings.TrimPrefix(name, "-")
+	var err error
+	switch name {
+	case "config":
+		return fmt.Errorf("the garden map is chosen by the launcher and cannot be set")
+	case "log":
+		o.Log, err = strconv.ParseBool(value)
+	case "time-scale":
+		o.TimeScale, err = strconv.ParseFloat(value, 64)
+	case "screen-width":
+		o.ScreenWidth, err = strconv.Atoi(value)
+	case "screen-height":
+		o.ScreenHeight, err = strconv.Atoi(value)
+	case "quality-level":
+		o.QualityLevel, err = strconv.Atoi(value)
+	default:
+		o.ExtraArgs = append(o.ExtraArgs, "-"+name, value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for simulator argument %s: %w", value, name, err)
+	}
+	return nil
+}
+
+// Validate reports options the simulator would not start with.
+func (o SimulatorOptions) Validate() error {
+	var errs []error
+	if o.TimeScale <= 0 {
+		errs = append(errs, fmt.Errorf("time scale must be positive, got %g", o.TimeScale))
+	}
+	if o.ScreenWidth <= 0 || o.ScreenHeight <= 0 {
+		errs = append(errs, fmt.Errorf("screen size must be positive, got %dx%d", o.ScreenWidth, o.ScreenHeight))
+	}
+	if o.QualityLevel < 0 {
+		errs = append(errs, fmt.Errorf("quality level must not be negative, got %d", o.QualityLevel))
+	}
+	return errors.Join(errs...)
+}
+
+// Args returns the command line the simulator is started with to load the
+// garden map at mapPath.
+func (o SimulatorOptions) Args(mapPath string) []string {
 	args := []string{
 		"-config", mapPath,
-		"-log", "false",
-		"-time-scale", "1",
-		"-screen-width", "1280",
-		"-screen-height", "720",
-		"-quality-level", "6",
-	}
-	cmd := exec.Command(simPath, args...)
-	return cmd.Start()
+		"-log", strconv.FormatBool(o.Log),
+		"-time-scale", strconv.FormatFloat(o.TimeScale, 'g', -1, 64),
+		"-screen-width", strconv.Itoa(o.ScreenWidth),
+		"-screen-height", strconv.Itoa(o.ScreenHeight),
+		"-quality-level", strconv.Itoa(o.QualityLevel),
+	}
+	if o.Log && o.LogFile != "" {
+		args = append(args, "-logFile", o.LogFile)
+	}
+	return append(args, o.ExtraArgs...)
 }
@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Supervisor starts runners after the runners they depend on and stops
+// everything it started in reverse order.
+type Supervisor struct {
+	mu       sync.Mutex
+	runners  map[string]Runner
+	deps     map[string][]string
+	probes   map[string]readiness
+	restarts map[string]restartSpec
+	started  []string
+	closed   bool
+}
+
+type readiness struct {
+	probe   Probe
+	timeout time.Duration
+}
+
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		runners:  make(map[string]Runner),
+		deps:     make(map[string][]string),
+		probes:   make(map[string]readiness),
+		restarts: make(map[string]restartSpec),
+	}
+}
+
+// Add registers a runner under name. It is started after the runners named
+// in dependsOn.
+func (s *Supervisor) Add(name string, r Runner, dependsOn ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[name] = r
+	s.deps[name] = dependsOn
+}
+
+// Ready makes Start wait up to timeout for probe to report the named runner
+// as ready. Probes that observe output are attached to the runner, so Ready
+// has to be called before the runner is started.
+func (s *Supervisor) Ready(name string, probe Probe, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes[name] = readiness{probe: probe, timeout: timeout}
+	if w, ok := probe.(io.Writer); ok {
+		if r, ok := s.runners[name].(watcher); ok {
+			r.Watch(w)
+		}
+	}
+}
+
+// Restart watches the named runner once it is started. Every exit that is
+// not caused by Stop is logged with the exit code and, when the runner keeps
+// them, its last lines of output. The runner is then restarted as policy
+// allows and after is run once it is ready again.
+func (s *Supervisor) Restart(name string, policy RestartPolicy, after func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restarts[name] = restartSpec{policy: policy, after: after}
+}
+
+// Start starts the named runner and, first, any of its dependencies that are
+// not running. Each runner with a probe must become ready before Start moves
+// on.
+func (s *Supervisor) Start(ctx context.Context, name string) error {
+	return s.start(ctx, name, nil)
+}
+
+func (s *Supervisor) start(ctx context.Context, name string, visiting []string) error {
+	for _, v := range visiting {
+		if v == name {
+			return fmt.Errorf("dependency cycle: %v", append(visiting, name))
+		}
+	}
+
+	s.mu.Lock()
+	r, ok := s.runners[name]
+	deps := s.deps[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no runner named %s", name)
+	}
+	if r.Status() == StatusRunning {
+		return nil
+	}
+
+	for _, dep := range deps {
+		if err := s.start(ctx, dep, append(visiting, name)); err != nil {
+			return err
+		}
+	}
+
+	log.Debug("Starting process", "name", name)
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errClosed
+	}
+	if err := r.Start(ctx); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.started = append(s.started, name)
+	ready, hasProbe := s.probes[name]
+	spec, watched := s.restarts[name]
+	s.mu.Unlock()
+
+	if hasProbe {
+		log.Debug("Waiting for process to become ready", "name", name, "probe", ready.probe)
+		if err := WaitReady(ctx, name, r, ready.probe, ready.timeout); err != nil {
+			return err
+		}
+	}
+	if watched {
+		go s.monitor(ctx, name, r, spec)
+	}
+	return nil
+}
+
+// Run adds, starts and waits for a runner that is expected to exit by
+// itself, such as a test bundle. A runner still running under the same name
+// is stopped first so it is not lost track of. Run returns when ctx is done
+// and leaves stopping the runner to Shutdown.
+func (s *Supervisor) Run(ctx context.Context, name string, r Runner, dependsOn ...string) error {
+	s.mu.Lock()
+	earlier := s.runners[name]
+	s.mu.Unlock()
+	if earlier != nil && earlier != r && earlier.Status() == StatusRunning {
+		log.Debug("Stopping earlier run of process", "name", name)
+		if err := earlier.Stop(); err != nil {
+			return err
+		}
+	}
+
+	s.Add(name, r, dependsOn...)
+	if err := s.Start(ctx, name); err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- r.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Supervisor) Status() map[string]Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make(map[string]Status, len(s.runners))
+	for name, r := range s.runners {
+		statuses[name] = r.Status()
+	}
+	return statuses
+}
+
+// Shutdown stops every started runner in the reverse order they were started.
+func (s *Supervisor) Shutdown() error {
+	s.mu.Lock()
+	started := s.started
+	s.started = nil
+	s.closed = true
+	s.mu.Unlock()
+
+	var errs []error
+	stopped := make(map[string]bool)
+	for i := len(started) - 1; i >= 0; i-- {
+		name := started[i]
+		if stopped[name] {
+			continue
+		}
+		stopped[name] = true
+
+		s.mu.Lock()
+		r := s.runners[name]
+		s.mu.Unlock()
+		if r.Status() != StatusRunning {
+			continue
+		}
+		log.Debug("Stopping process", "name", name)
+		if err := r.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+const tailInterval = 250 * time.Millisecond
+
+// TailFile copies whole lines appended to the file at path to w until ctx is
+// done. The file does not need to exist yet, and it is read from the start
+// again when it is truncated or replaced by a shorter one.
+func TailFile(ctx context.Context, path string, w io.Writer) {
+	var f *os.File
+	var offset int64
+	var pending []byte
+	buf := make([]byte, 32*1024)
+
+	read := func() {
+		if f == nil {
+			var err error
+			if f, err = os.Open(path); err != nil {
+				f = nil
+				return
+			}
+		}
+		if info, err := f.Stat(); err == nil && info.Size() < offset {
+			f.Seek(0, io.SeekStart)
+			offset = 0
+			pending = nil
+		}
+		for {
+			n, err := f.Read(buf)
+			offset += int64(n)
+			pending = append(pending, buf[:n]...)
+			if err != nil || n == 0 {
+				break
+			}
+		}
+		if i := bytes.LastIndexByte(pending, '\n'); i >= 0 {
+			w.Write(pending[:i+1])
+			pending = pending[i+1:]
+		}
+	}
+
+	ticker := time.NewTicker(tailInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			read()
+			if len(pending) > 0 {
+				w.Write(append(pending, '\n'))
+			}
+			if f != nil {
+				f.Close()
+			}
+			return
+		case <-ticker.C:
+			read()
+		}
+	}
+}
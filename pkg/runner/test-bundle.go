@@ -4,7 +4,9 @@ import (
 	"context"
 	"io"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/log"
 )
@@ -12,36 +14,108 @@ import (
 type TestBundleRunner struct {
 	tifConsolePath string
 	logger         io.Writer
+	parser         *TestResultParser
+}
+
+// TestBundle is one test bundle run by TifConsole. Its output is parsed into
+// TestResults.
+type TestBundle struct {
+	*process
+	path      string
+	collector *resultCollector
 }
 
 type TifConsoleLogger struct {
-	logger *log.Logger
+	logger  *log.Logger
+	record  *log.Logger
+	filter  *Filter
+	mu      sync.Mutex
+	repeats collapser
 }
 
-func NewTestBundleRunner(tifConsolePath string, logger io.Writer) *TestBundleRunner {
+func NewTestBundleRunner(tifConsolePath string, logger io.Writer, parser *TestResultParser) *TestBundleRunner {
 	return &TestBundleRunner{
 		tifConsolePath: tifConsolePath,
 		logger:         logger,
+		parser:         parser,
 	}
 }
 
-func NewTifConsoleLogger(logger *log.Logger) *TifConsoleLogger {
+// NewTifConsoleLogger returns a logger of TifConsole output. filter only
+// decides what logger shows; record, when not nil, gets every line. filter
+// may be nil.
+func NewTifConsoleLogger(logger, record *log.Logger, filter *Filter) *TifConsoleLogger {
 	return &TifConsoleLogger{
 		logger: logger,
+		record: record,
+		filter: filter,
 	}
 }
 
-func (r *TestBundleRunner) Run(ctx context.Context, bundlePath string, args ...string) error {
+// Bundle returns a Runner that runs the test bundle once with TifConsole.
+func (r *TestBundleRunner) Bundle(bundlePath string, args ...string) *TestBundle {
 	cmdArgs := append([]string{bundlePath}, args...)
-	cmd := exec.CommandContext(ctx, r.tifConsolePath, cmdArgs...)
-	cmd.Stdout = r.logger
-	cmd.Stderr = r.logger
-	return cmd.Run()
+	b := &TestBundle{path: bundlePath}
+	b.process = newConsoleProcess("tifconsole", func() *exec.Cmd {
+		b.collector = r.parser.collector(filepath.Base(bundlePath))
+		output := io.MultiWriter(r.logger, b.collector)
+		cmd := exec.Command(r.tifConsolePath, cmdArgs...)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		return cmd
+	})
+	return b
+}
+
+// Results returns the results of the last run once it has exited, or nil.
+func (b *TestBundle) Results() *TestResults {
+	info := b.ExitInfo()
+	if info == nil || b.collector == nil {
+		return nil
+	}
+	return b.collector.finish(info)
+}
+
+func (r *TestBundleRunner) Run(ctx context.Context, bundlePath string, args ...string) error {
+	p := r.Bundle(bundlePath, args...)
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- p.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+		p.Stop()
+		return ctx.Err()
+	}
 }
 
 func (l *TifConsoleLogger) Write(bytes []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	str := string(bytes)
 	str = strings.TrimSuffix(str, "\n")
-	l.logger.Print(str)
+	for _, line := range strings.Split(str, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if l.record != nil {
+			l.record.Print(line)
+		}
+		if !l.filter.Allow("", log.InfoLevel, line) {
+			continue
+		}
+		show, repeats := l.repeats.seen(line, l.filter.Collapses())
+		if repeats > 0 {
+			l.logger.Print("Previous line repeated", "times", repeats)
+		}
+		if show {
+			l.logger.Print(l.filter.Decorate(line))
+		}
+	}
 	return len(bytes), nil
 }
@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions a trigger can run when it matches.
+const (
+	TriggerAlert  = "alert"
+	TriggerPause  = "pause"
+	TriggerMarker = "marker"
+	TriggerHook   = "hook"
+	TriggerStop   = "stop"
+)
+
+// TriggerRule runs Actions for every line of output matching Pattern.
+type TriggerRule struct {
+	Name string `mapstructure:"name"`
+	// Sources limits the rule to the output of these processes, such as
+	// winmower, tifconsole or simulator. A source also matches the processes
+	// of every robot, winmower matches winmower:<serial>. Empty matches all.
+	Sources []string `mapstructure:"sources"`
+	Pattern string   `mapstructure:"pattern"`
+	Actions []string `mapstructure:"actions"`
+	// Hook is the shell command the hook action runs.
+	Hook string `mapstructure:"hook"`
+	// ExitCode is what the stop action exits with, 1 when not set.
+	ExitCode int `mapstructure:"exitCode"`
+	// Cooldown is how long matches are ignored after the rule fired, so a
+	// repeated line does not run the actions for every repetition.
+	Cooldown time.Duration `mapstructure:"cooldown"`
+	// Once makes the rule fire only for its first match.
+	Once bool `mapstructure:"once"`
+}
+
+type TriggerMatch struct {
+	Rule   *TriggerRule
+	Source string
+	Line   string
+	Time   time.Time
+}
+
+// Triggers match the output of processes against trigger rules.
+type Triggers struct {
+	mu    sync.Mutex
+	rules []trigger
+	fire  func(TriggerMatch)
+}
+
+type trigger struct {
+	rule  TriggerRule
+	re    *regexp.Regexp
+	fired time.Time
+}
+
+// NewTriggers validates rules. fire is called for every match from the
+// goroutine writing the output, so it must not block for long.
+func NewTriggers(rules []TriggerRule, fire func(TriggerMatch)) (*Triggers, error) {
+	t := &Triggers{fire: fire}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("trigger %d", i+1)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", rule.Name, err)
+		}
+		if len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("%s: no actions", rule.Name)
+		}
+		for _, action := range rule.Actions {
+			switch action {
+			case TriggerAlert, TriggerPause, TriggerMarker, TriggerStop:
+			case TriggerHook:
+				if rule.Hook == "" {
+					return nil, fmt.Errorf("%s: hook action without a hook command", rule.Name)
+				}
+			default:
+				return nil, fmt.Errorf("%s: unknown action %q", rule.Name, action)
+			}
+		}
+		if rule.Cooldown < 0 {
+			return nil, fmt.Errorf("%s: negative cooldown", rule.Name)
+		}
+		if rule.ExitCode == 0 {
+			rule.ExitCode = 1
+		}
+		t.rules = append(t.rules, trigger{rule: rule, re: re})
+	}
+	return t, nil
+}
+
+func (t *Triggers) Empty() bool {
+	return len(t.rules) == 0
+}
+
+// Writer returns a writer that matches the lines written to it as output of
+// source.
+func (t *Triggers) Writer(source string) io.Writer {
+	return &triggerWriter{triggers: t, source: source}
+}
+
+func (t *Triggers) match(source, line string) {
+	for i := range t.rules {
+		tr := &t.rules[i]
+		if !tr.appliesTo(source) || !tr.re.MatchString(line) {
+			continue
+		}
+		now := time.Now()
+		if !t.take(tr, now) {
+			continue
+		}
+		t.fire(TriggerMatch{Rule: &tr.rule, Source: source, Line: line, Time: now})
+	}
+}
+
+// take reports whether tr may fire at now, and if so records that it did.
+// Sources are written from their own goroutines, so this is locked.
+func (t *Triggers) take(tr *trigger, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !tr.fired.IsZero() {
+		if tr.rule.Once || now.Sub(tr.fired) < tr.rule.Cooldown {
+			return false
+		}
+	}
+	tr.fired = now
+	return true
+}
+
+func (t *trigger) appliesTo(source string) bool {
+	if len(t.rule.Sources) == 0 {
+		return true
+	}
+	for _, s := range t.rule.Sources {
+		if source == s || strings.HasPrefix(source, s+":") {
+			return true
+		}
+	}
+	return false
+}
+
+type triggerWriter struct {
+	triggers *Triggers
+	source   string
+	mu       sync.Mutex
+	partial  string
+}
+
+func (w *triggerWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := strings.Split(w.partial+string(p), "\n")
+	w.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		w.triggers.match(w.source, strings.TrimSuffix(line, "\r"))
+	}
+	return len(p), nil
+}
@@ -4,73 +4,166 @@ import (
 	"context"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
 type WinMowerRunner struct {
+	*process
 	dir    string
 	path   string
+	args   []string
 	logger *WinMowerLogger
-	cmd    *exec.Cmd
+	recent *LineBuffer
 }
 
+// recentLines is how many lines of output are kept to explain a crash.
+const recentLines = 20
+
+// WinMowerLogger logs the output of WinMower one entry at a time. An entry
+// is logged once the next one starts or WinMower has been quiet for
+// entryFlushDelay, so lines that continue it are not split off.
 type WinMowerLogger struct {
-	logger *log.Logger
+	logger  *log.Logger
+	record  *log.Logger
+	parser  *WinMowerLogParser
+	filter  *Filter
+	mu      sync.Mutex
+	repeats collapser
+	partial string
+	pending *WinMowerEntry
+	timer   *time.Timer
 }
 
-func NewWinMowerRunner(dir, path string, logger *WinMowerLogger) *WinMowerRunner {
-	return &WinMowerRunner{
+const entryFlushDelay = 200 * time.Millisecond
+
+func NewWinMowerRunner(dir, path string, args []string, logger *WinMowerLogger) *WinMowerRunner {
+	r := &WinMowerRunner{
 		dir:    dir,
 		path:   path,
+		args:   args,
 		logger: logger,
+		recent: NewLineBuffer(recentLines),
 	}
+	r.process = newConsoleProcess("winmower", r.command)
+	r.Watch(r.recent)
+	return r
 }
 
+// Start starts WinMower. RecentLines only returns lines of the new run.
 func (r *WinMowerRunner) Start(ctx context.Context) error {
-	r.cmd = exec.CommandContext(ctx, r.path)
-	r.cmd.Dir = r.dir
-	r.cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: false}
-	r.cmd.Stdout = r.logger
-	r.cmd.Stderr = r.logger
-	return r.cmd.Start()
+	r.recent.Reset()
+	return r.process.Start(ctx)
+}
+
+// RecentLines returns the last lines WinMower wrote.
+func (r *WinMowerRunner) RecentLines() []string {
+	return r.recent.Lines()
 }
 
-func (r *WinMowerRunner) Stop() error {
-	return r.cmd.Cancel()
+func (r *WinMowerRunner) command() *exec.Cmd {
+	cmd := exec.Command(r.path, r.args...)
+	cmd.Dir = r.dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: false}
+	cmd.Stdout = r.logger
+	cmd.Stderr = r.logger
+	return cmd
 }
 
-func NewWinMowerLogger(logger *log.Logger) *WinMowerLogger {
+// NewWinMowerLogger returns a logger of WinMower output. filter only decides
+// what logger shows; record, when not nil, gets every entry as it was parsed.
+// filter may be nil.
+func NewWinMowerLogger(logger, record *log.Logger, parser *WinMowerLogParser, filter *Filter) *WinMowerLogger {
 	return &WinMowerLogger{
 		logger: logger,
+		record: record,
+		parser: parser,
+		filter: filter,
 	}
 }
 
 func (r *WinMowerLogger) Write(bytes []byte) (int, error) {
-	str := string(bytes)
-	str = strings.TrimSuffix(str, "\n")
-
-	lines := strings.Split(str, "\n")
-	first := lines[0]
-
-	switch {
-	case strings.Contains(first, "ERROR"):
-		r.logger.Error(first)
-	case strings.Contains(first, "WARNING"):
-		r.logger.Warn(first)
-	case strings.Contains(first, "INFO"):
-		r.logger.Info(first)
-	case strings.Contains(first, "DEBUG"):
-		r.logger.Debug(first)
-	default:
-		r.logger.Info(first)
-	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	rest := lines[1:]
-	for _, line := range rest {
-		r.Write([]byte(line))
+	lines := strings.Split(r.partial+string(bytes), "\n")
+	r.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		r.line(strings.TrimSuffix(line, "\r"))
 	}
 
+	if r.timer == nil {
+		r.timer = time.AfterFunc(entryFlushDelay, r.Flush)
+	} else {
+		r.timer.Reset(entryFlushDelay)
+	}
 	return len(bytes), nil
 }
+
+func (r *WinMowerLogger) line(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	entry, continues := r.parser.Parse(line)
+	if continues && r.pending != nil {
+		r.pending.Detail = append(r.pending.Detail, line)
+		return
+	}
+	if continues {
+		entry = WinMowerEntry{Level: log.InfoLevel, Message: line}
+	}
+	r.flush()
+	r.pending = &entry
+}
+
+// Flush logs the entry still waiting for lines that continue it, and an
+// unfinished last line.
+func (r *WinMowerLogger) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.partial != "" {
+		r.line(strings.TrimSuffix(r.partial, "\r"))
+		r.partial = ""
+	}
+	r.flush()
+}
+
+func (r *WinMowerLogger) flush() {
+	if r.pending != nil {
+		r.log(*r.pending)
+		r.pending = nil
+	}
+}
+
+func (r *WinMowerLogger) log(e WinMowerEntry) {
+	if r.record != nil {
+		logAt(r.record, e.Level, e.Message, e.KeyVals()...)
+	}
+	if !r.filter.Allow(e.Module, e.Level, e.Message) {
+		return
+	}
+	show, repeats := r.repeats.seen(e.Module+"\x00"+e.Level.String()+"\x00"+e.Message, r.filter.Collapses())
+	if repeats > 0 {
+		r.logger.Info("Previous entry repeated", "times", repeats)
+	}
+	if !show {
+		return
+	}
+	logAt(r.logger, e.Level, r.filter.Decorate(e.Message), e.KeyVals()...)
+}
+
+func logAt(l *log.Logger, level log.Level, msg string, keyvals ...any) {
+	switch level {
+	case log.ErrorLevel:
+		l.Error(msg, keyvals...)
+	case log.WarnLevel:
+		l.Warn(msg, keyvals...)
+	case log.DebugLevel:
+		l.Debug(msg, keyvals...)
+	default:
+		l.Info(msg, keyvals...)
+	}
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// DefaultWinMowerLogPattern matches WinMower log lines such as
+//
+//	2024-05-02 13:45:10.123 [INFO] [Navigation] Entered work area 1
+//	13:45:10.123 WARNING Loop: Boundary signal weak
+//
+// Patterns use the named groups time, level, module and msg. Only msg is
+// required. Lines that do not match are entries at the level they mention,
+// or continue the entry before them, see WinMowerLogParser.Parse.
+const DefaultWinMowerLogPattern = `^(?:(?P<time>\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?|\d{2}:\d{2}:\d{2}(?:[.,]\d+)?)\s+)?\[?(?P<level>ERROR|FATAL|CRITICAL|WARNING|WARN|INFO|DEBUG|TRACE)\]?\s+(?:\[(?P<module>[^\]]+)\]|(?P<module>[\w.\-/]+):)?\s*(?P<msg>.*)$`
+
+// WinMowerEntry is one log entry of WinMower. Lines that continue it, such
+// as stack traces, are kept in Detail.
+type WinMowerEntry struct {
+	Time    string
+	Level   log.Level
+	Module  string
+	Message string
+	Detail  []string
+}
+
+type WinMowerLogParser struct {
+	pattern *regexp.Regexp
+}
+
+func NewWinMowerLogParser(pattern string) (*WinMowerLogParser, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.SubexpIndex("msg") < 0 {
+		return nil, fmt.Errorf("pattern has no msg group")
+	}
+	return &WinMowerLogParser{pattern: re}, nil
+}
+
+// Parse parses one line of WinMower output. Lines that continue the entry
+// before them, such as the indented lines of a stack trace, report continues.
+// Other lines that do not match the pattern are entries of their own, with
+// the level they mention.
+func (p *WinMowerLogParser) Parse(line string) (e WinMowerEntry, continues bool) {
+	m := p.pattern.FindStringSubmatch(line)
+	if m == nil {
+		if continuationLine.MatchString(line) {
+			return WinMowerEntry{}, true
+		}
+		return WinMowerEntry{Level: mentionedLevel(line), Message: line}, false
+	}
+
+	e = WinMowerEntry{Level: log.InfoLevel}
+	for i, name := range p.pattern.SubexpNames() {
+		if m[i] == "" {
+			continue
+		}
+		switch name {
+		case "time":
+			e.Time = m[i]
+		case "level":
+			e.Level = winMowerLevel(m[i])
+		case "module":
+			e.Module = m[i]
+		case "msg":
+			e.Message = m[i]
+		}
+	}
+	return e, false
+}
+
+// continuationLine matches lines that belong to the entry before them:
+// indented lines, stack frames and exception lines.
+var continuationLine = regexp.MustCompile(`^(?:\s|at\s|---|Caused by|Traceback|[\w.]+(?:Exception|Error)(?::|$))`)
+
+// mentionedLevel finds the level of a line that does not match the pattern
+// from the level names it contains.
+func mentionedLevel(line string) log.Level {
+	switch {
+	case strings.Contains(line, "ERROR"), strings.Contains(line, "FATAL"):
+		return log.ErrorLevel
+	case strings.Contains(line, "WARNING"), strings.Contains(line, "WARN"):
+		return log.WarnLevel
+	case strings.Contains(line, "DEBUG"):
+		return log.DebugLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+func winMowerLevel(level string) log.Level {
+	switch strings.ToUpper(level) {
+	case "ERROR", "FATAL", "CRITICAL":
+		return log.ErrorLevel
+	case "WARNING", "WARN":
+		return log.WarnLevel
+	case "DEBUG", "TRACE":
+		return log.DebugLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+// KeyVals returns the fields of e as logger key/value pairs.
+func (e WinMowerEntry) KeyVals() []any {
+	var keyvals []any
+	if e.Module != "" {
+		keyvals = append(keyvals, "module", e.Module)
+	}
+	if e.Time != "" {
+		keyvals = append(keyvals, "wmTime", e.Time)
+	}
+	if len(e.Detail) > 0 {
+		keyvals = append(keyvals, "detail", strings.Join(e.Detail, "\n"))
+	}
+	return keyvals
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestWinMowerLogParserParse(t *testing.T) {
+	parser, err := NewWinMowerLogParser(DefaultWinMowerLogPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line      string
+		continues bool
+		want      WinMowerEntry
+	}{
+		{
+			line: "2024-05-02 13:45:10.123 [INFO] [Navigation] Entered work area 1",
+			want: WinMowerEntry{Time: "2024-05-02 13:45:10.123", Level: log.InfoLevel, Module: "Navigation", Message: "Entered work area 1"},
+		},
+		{
+			line: "13:45:10.123 WARNING Loop: Boundary signal weak",
+			want: WinMowerEntry{Time: "13:45:10.123", Level: log.WarnLevel, Module: "Loop", Message: "Boundary signal weak"},
+		},
+		{
+			line: "[ERROR] Unhandled exception in state machine",
+			want: WinMowerEntry{Level: log.ErrorLevel, Message: "Unhandled exception in state machine"},
+		},
+		{
+			line: "WARNING: low battery",
+			want: WinMowerEntry{Level: log.WarnLevel, Message: "WARNING: low battery"},
+		},
+		{
+			line: "ERROR: could not open /dev/ttyS0",
+			want: WinMowerEntry{Level: log.ErrorLevel, Message: "ERROR: could not open /dev/ttyS0"},
+		},
+		{
+			line: "Listening on 127.0.0.1:4250",
+			want: WinMowerEntry{Level: log.InfoLevel, Message: "Listening on 127.0.0.1:4250"},
+		},
+		{line: "System.NullReferenceException: Object reference not set to an instance of an object.", continues: true},
+		{line: "   at WinMower.StateMachine.Step() in C:\\src\\StateMachine.cs:line 42", continues: true},
+		{line: "   --- End of inner exception stack trace ---", continues: true},
+		{line: "\tat WinMower.Program.Main(String[] args)", continues: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, continues := parser.Parse(tt.line)
+			if continues != tt.continues {
+				t.Fatalf("continues = %v, want %v", continues, tt.continues)
+			}
+			if tt.continues {
+				return
+			}
+			if got.Time != tt.want.Time || got.Level != tt.want.Level || got.Module != tt.want.Module || got.Message != tt.want.Message {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWinMowerLoggerGroupsStackTraces(t *testing.T) {
+	parser, err := NewWinMowerLogParser(DefaultWinMowerLogPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	logger := NewWinMowerLogger(log.NewWithOptions(&out, log.Options{Formatter: log.LogfmtFormatter}), nil, parser, nil)
+
+	logger.Write([]byte(strings.Join([]string{
+		"13:45:10.123 ERROR StateMachine: Unhandled exception",
+		"System.NullReferenceException: Object reference not set to an instance of an object.",
+		"   at WinMower.StateMachine.Step() in C:\\src\\StateMachine.cs:line 42",
+		"WARNING: low battery",
+		"",
+	}, "\r\n")))
+	logger.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d entries, want 2:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "level=error") || !strings.Contains(lines[0], "StateMachine.cs:line 42") {
+		t.Errorf("stack trace not kept with its entry: %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "level=warn") {
+		t.Errorf("WARNING line not logged as a warning: %s", lines[1])
+	}
+}
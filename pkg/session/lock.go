@@ -0,0 +1,78 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/runner"
+)
+
+// Lock records what a launch resolved to so it can be reproduced exactly
+// with --lock.
+type Lock struct {
+	CreatedAt          time.Time `json:"createdAt"`
+	SerialNumber       string    `json:"serialNumber"`
+	Platform           string    `json:"platform"`
+	WinMower           Build     `json:"winMower"`
+	Simulator          Build     `json:"simulator"`
+	GSPHash            string    `json:"gspHash"`
+	StartTriggerBundle string    `json:"startTriggerBundle"`
+	// StartTriggerHash is the SHA-256 of the start trigger bundle.
+	StartTriggerHash string `json:"startTriggerHash"`
+	// WinMowerAddress is how the WinMower address was chosen, such as auto
+	// or a host:port, and is resolved again by --lock.
+	WinMowerAddress string `json:"winMowerAddress"`
+	// The arguments are replayed by --lock with {map} replaced by the map of
+	// the locked GSP and {host}:{port} by the resolved WinMower address. The
+	// simulator log file is not part of them.
+	WinMowerArgs     []string                 `json:"winMowerArgs"`
+	SimulatorArgs    []string                 `json:"simulatorArgs"`
+	TestBundleArgs   []string                 `json:"testBundleArgs"`
+	SimulatorOptions *runner.SimulatorOptions `json:"simulatorOptions"`
+}
+
+// LockName returns the file name of a lockfile written at t.
+func LockName(serialNumber, platform string, t time.Time) string {
+	return fmt.Sprintf("%s-%s-%s.lock.json", serialNumber, platform, t.Format("20060102-150405"))
+}
+
+// LatestLock returns the newest lockfile for serialNumber and platform in dir,
+// or an empty string when there is none.
+func LatestLock(dir, serialNumber, platform string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, serialNumber+"-"+platform+"-*.lock.json"))
+	if err != nil || len(paths) == 0 {
+		return "", err
+	}
+	// The time in the name sorts in order.
+	sort.Strings(paths)
+	return paths[len(paths)-1], nil
+}
+
+func WriteLock(path string, l *Lock) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func ReadLock(path string) (*Lock, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var l Lock
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("error unmarshalling lockfile %s: %v", path, err)
+	}
+	if l.SerialNumber == "" || l.Platform == "" || l.WinMower.BuildId == "" || l.Simulator.BuildId == "" ||
+		l.GSPHash == "" || l.StartTriggerHash == "" || l.SimulatorOptions == nil {
+		return nil, fmt.Errorf("lockfile %s is incomplete", path)
+	}
+	return &l, nil
+}
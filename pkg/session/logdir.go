@@ -0,0 +1,236 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-logfmt/logfmt"
+)
+
+// CombinedLogName is the file in a LogDir every source writes its entries to
+// as newline delimited JSON.
+const CombinedLogName = "combined.ndjson"
+
+const logDirTimeFormat = "20060102-150405"
+
+// ansiEscape matches the color codes of highlighted messages, which are left
+// out of the combined log.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// LogDir is the log directory of one launch. It holds the raw output of
+// every process and a combined log of all log entries.
+type LogDir struct {
+	Path string
+
+	mu       sync.Mutex
+	combined *os.File
+	files    []*os.File
+}
+
+// Entry is one line of the combined log.
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Source  string         `json:"source"`
+	Level   string         `json:"level"`
+	Message string         `json:"msg"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// CreateLogDir creates a log directory named after now and name in root.
+func CreateLogDir(root, name string, now time.Time) (*LogDir, error) {
+	path := filepath.Join(root, now.Format(logDirTimeFormat)+"-"+name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log dir: %w", err)
+	}
+	combined, err := os.OpenFile(filepath.Join(path, CombinedLogName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create combined log: %w", err)
+	}
+	return &LogDir{Path: path, combined: combined}, nil
+}
+
+// RawLogName returns the name of the file with the unprocessed output of
+// source. Colons, as in winmower:<serial>, would open an NTFS alternate data
+// stream.
+func RawLogName(source string) string {
+	return strings.ReplaceAll(source, ":", "-") + ".log"
+}
+
+// Raw returns a file in the log directory for the unprocessed output of
+// source.
+func (d *LogDir) Raw(source string) (io.Writer, error) {
+	f, err := os.OpenFile(filepath.Join(d.Path, RawLogName(source)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s log: %w", source, err)
+	}
+	d.mu.Lock()
+	d.files = append(d.files, f)
+	d.mu.Unlock()
+	return f, nil
+}
+
+// Logger returns a logger whose entries are written to the combined log as
+// coming from source and then logged by terminal, if not nil.
+func (d *LogDir) Logger(source string, terminal *log.Logger) *log.Logger {
+	// logfmt, unlike JSON, keeps the keyvals in the order they were logged.
+	logger := log.NewWithOptions(&entryWriter{dir: d, source: source, terminal: terminal}, log.Options{
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339Nano,
+		Formatter:       log.LogfmtFormatter,
+		Level:           log.DebugLevel,
+	})
+	return logger
+}
+
+func (d *LogDir) write(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err = d.combined.Write(append(b, '\n'))
+	return err
+}
+
+func (d *LogDir) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	errs := []error{d.combined.Close()}
+	for _, f := range d.files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// entryWriter receives the logfmt records of a logger, records them in the
+// combined log and hands them on to the terminal logger in the same order.
+type entryWriter struct {
+	dir      *LogDir
+	source   string
+	terminal *log.Logger
+}
+
+func (w *entryWriter) Write(p []byte) (int, error) {
+	dec := logfmt.NewDecoder(bytes.NewReader(p))
+	for dec.ScanRecord() {
+		e := Entry{Source: w.source, Fields: make(map[string]any)}
+		var msg string
+		var keyvals []any
+		for dec.ScanKeyval() {
+			k, v := string(dec.Key()), string(dec.Value())
+			switch k {
+			case log.TimestampKey:
+				e.Time, _ = time.Parse(time.RFC3339Nano, v)
+			case log.LevelKey:
+				e.Level = v
+			case log.MessageKey:
+				msg = v
+				e.Message = ansiEscape.ReplaceAllString(msg, "")
+			case log.PrefixKey:
+			default:
+				e.Fields[k] = v
+				keyvals = append(keyvals, k, v)
+			}
+		}
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if err := w.dir.write(e); err != nil {
+			return 0, err
+		}
+
+		if w.terminal == nil {
+			continue
+		}
+		switch e.Level {
+		case "debug":
+			w.terminal.Debug(msg, keyvals...)
+		case "info":
+			w.terminal.Info(msg, keyvals...)
+		case "warn":
+			w.terminal.Warn(msg, keyvals...)
+		case "error", "fatal":
+			w.terminal.Error(msg, keyvals...)
+		default:
+			w.terminal.Print(msg, keyvals...)
+		}
+	}
+	if err := dec.Err(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// LogDirs returns the log directories in root, newest first.
+func LogDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			dirs = append(dirs, filepath.Join(root, e.Name()))
+		}
+	}
+	// Names start with their creation time, so they sort chronologically.
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	return dirs, nil
+}
+
+// PruneLogDirs removes the log directories in root beyond the newest
+// maxDirs and those older than maxAge, except keep. Zero disables a limit.
+func PruneLogDirs(root string, maxDirs int, maxAge time.Duration, keep string) ([]string, error) {
+	dirs, err := LogDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var errs []error
+	kept := 0
+	for _, dir := range dirs {
+		if dir == keep {
+			kept++
+			continue
+		}
+		created, ok := logDirTime(dir)
+		tooOld := ok && maxAge > 0 && time.Since(created) > maxAge
+		tooMany := maxDirs > 0 && kept >= maxDirs
+		if !tooOld && !tooMany {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	return removed, errors.Join(errs...)
+}
+
+func logDirTime(dir string) (time.Time, bool) {
+	name := filepath.Base(dir)
+	if len(name) < len(logDirTimeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(logDirTimeFormat, name[:len(logDirTimeFormat)], time.Local)
+	return t, err == nil
+}
@@ -0,0 +1,160 @@
+package session
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Tifufu/gsim-web-launch/pkg/cache"
+	"github.com/Tifufu/gsim-web-launch/pkg/ext"
+)
+
+const (
+	manifestName     = "session.json"
+	gspDirName       = "gsp"
+	wmFsDirName      = "winmower-fs"
+	startTriggerName = "start-trigger.zip"
+	formatVersion    = 1
+)
+
+// Manifest records everything needed to recreate a session on another
+// machine. It is stored as session.json at the root of an exported archive.
+type Manifest struct {
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"createdAt"`
+	SerialNumber string    `json:"serialNumber"`
+	Platform     string    `json:"platform"`
+	WinMower     Build     `json:"winMower"`
+	Simulator    Build     `json:"simulator"`
+	// Lock is the lockfile of the exported launch. Its start trigger bundle
+	// is stored in the archive.
+	Lock *Lock `json:"lock"`
+}
+
+type Build struct {
+	BundleType string `json:"bundleType"`
+	BuildId    string `json:"buildId"`
+}
+
+// Archive is an exported session unpacked into a temporary directory.
+type Archive struct {
+	Manifest *Manifest
+	dir      string
+}
+
+// Export writes the session to dest as a zip archive containing the manifest,
+// the GSP, the start trigger bundle of the lock and, if wmFsDir exists, a
+// snapshot of the winmower filesystem.
+func Export(dest string, m *Manifest, gspDir, wmFsDir string) (err error) {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	m.Version = formatVersion
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+
+	if err := ext.ZipDir(zw, gspDir, gspDirName, cache.IsMetadata); err != nil {
+		return fmt.Errorf("failed to add GSP: %w", err)
+	}
+	if err := addFile(zw, m.Lock.StartTriggerBundle, startTriggerName); err != nil {
+		return fmt.Errorf("failed to add start trigger bundle: %w", err)
+	}
+	if _, err := os.Stat(wmFsDir); err == nil {
+		if err := ext.ZipDir(zw, wmFsDir, wmFsDirName, nil); err != nil {
+			return fmt.Errorf("failed to add winmower filesystem: %w", err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func addFile(zw *zip.Writer, src, name string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Open unpacks an exported session. Close removes the unpacked files.
+func Open(archive string) (*Archive, error) {
+	dir, err := os.MkdirTemp("", "gsim-session-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := ext.Unzip(archive, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%s is not a session archive: %w", archive, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("error unmarshalling %s: %v", manifestName, err)
+	}
+	if m.Version != formatVersion {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("unsupported session archive version %d", m.Version)
+	}
+	if m.Lock == nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%s has no lockfile", archive)
+	}
+
+	return &Archive{Manifest: &m, dir: dir}, nil
+}
+
+func (a *Archive) GSPDir() string {
+	return filepath.Join(a.dir, gspDirName)
+}
+
+func (a *Archive) StartTriggerBundle() string {
+	return filepath.Join(a.dir, startTriggerName)
+}
+
+// WinMowerFSDir returns the unpacked winmower filesystem snapshot or an empty
+// string if the session had none.
+func (a *Archive) WinMowerFSDir() string {
+	dir := filepath.Join(a.dir, wmFsDirName)
+	if _, err := os.Stat(dir); err != nil {
+		return ""
+	}
+	return dir
+}
+
+func (a *Archive) Close() error {
+	return os.RemoveAll(a.dir)
+}